@@ -0,0 +1,111 @@
+/*
+Copyright 2020 Testutil Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// ServerSideApply applies the object using server-side apply as the field
+// manager. The object should only contain the fields the manager has an
+// opinion about. If force is set, conflicting fields are taken over from
+// other managers, otherwise a conflict is returned, which can be checked
+// using apierrors.IsConflict. The object is updated in place with the result,
+// so its managed fields can be inspected using FieldManagers.
+func (c *Client) ServerSideApply(ctx context.Context, obj runtime.Object, fieldManager string, force bool) error {
+	gvk, err := apiutil.GVKForObject(obj, c.scheme)
+	if err != nil {
+		return err
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	// The apply configuration has to contain the kind and must not contain
+	// managed fields, e.g. if a retrieved object is applied again.
+	obj.GetObjectKind().SetGroupVersionKind(gvk)
+	accessor.SetManagedFields(nil)
+	opts := []client.PatchOption{client.FieldOwner(fieldManager)}
+	if force {
+		opts = append(opts, client.ForceOwnership)
+	}
+	return c.Patch(ctx, obj, client.Apply, opts...)
+}
+
+// FieldManagers returns the names of all managers, which manage the field at
+// the path or any field nested within it, according to the managed fields of
+// the object. Path elements are field names, e.g. `"spec", "replicas"`.
+// Elements of associative lists can be addressed using the keys of the
+// managed fields format directly, e.g. `k:{"name":"nginx"}`.
+//
+// Managers using server-side apply and managers updating the object, e.g.
+// `kubectl` or controllers, are both included.
+func FieldManagers(obj runtime.Object, path ...string) ([]string, error) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, err
+	}
+	managers := []string{}
+	seen := map[string]bool{}
+	for _, entry := range accessor.GetManagedFields() {
+		if entry.FieldsV1 == nil || seen[entry.Manager] {
+			continue
+		}
+		fields := map[string]interface{}{}
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+			return nil, fmt.Errorf("failed to decode managed fields of %s: %w", entry.Manager, err)
+		}
+		if managesField(fields, path) {
+			managers = append(managers, entry.Manager)
+			seen[entry.Manager] = true
+		}
+	}
+	return managers, nil
+}
+
+// managesField checks whether the path is part of the fields, which are
+// encoded in the FieldsV1 format, e.g. `{"f:spec":{"f:replicas":{}}}`.
+func managesField(fields map[string]interface{}, path []string) bool {
+	for _, element := range path {
+		if !isFieldsV1Key(element) {
+			element = "f:" + element
+		}
+		next, ok := fields[element].(map[string]interface{})
+		if !ok {
+			return false
+		}
+		fields = next
+	}
+	return true
+}
+
+func isFieldsV1Key(element string) bool {
+	for _, prefix := range []string{"f:", "k:", "v:", "i:"} {
+		if strings.HasPrefix(element, prefix) {
+			return true
+		}
+	}
+	return element == "."
+}
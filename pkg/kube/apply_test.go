@@ -0,0 +1,107 @@
+/*
+Copyright 2020 Testutil Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"context"
+
+	"github.com/kubism/testutil/pkg/rand"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+func genApplyConfigMap(name string, data map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      name,
+		},
+		Data: data,
+	}
+}
+
+var _ = Describe("ServerSideApply", func() {
+	It("creates and updates objects", func() {
+		name := "cm-" + rand.String(5)
+		configMap := genApplyConfigMap(name, map[string]string{"foo": "bar"})
+		gomega.Expect(k8sClient.ServerSideApply(context.Background(), configMap, "operator", false)).To(gomega.Succeed())
+		defer k8sClient.Delete(context.Background(), configMap) // nolint:errcheck
+		gomega.Expect(configMap.UID).ToNot(gomega.BeEmpty())
+		configMap = genApplyConfigMap(name, map[string]string{"foo": "baz"})
+		gomega.Expect(k8sClient.ServerSideApply(context.Background(), configMap, "operator", false)).To(gomega.Succeed())
+		gomega.Expect(configMap.Data).To(gomega.HaveKeyWithValue("foo", "baz"))
+	})
+	It("reports conflicts unless forced", func() {
+		name := "cm-" + rand.String(5)
+		configMap := genApplyConfigMap(name, map[string]string{"foo": "bar"})
+		gomega.Expect(k8sClient.ServerSideApply(context.Background(), configMap, "user", false)).To(gomega.Succeed())
+		defer k8sClient.Delete(context.Background(), configMap) // nolint:errcheck
+		err := k8sClient.ServerSideApply(context.Background(),
+			genApplyConfigMap(name, map[string]string{"foo": "baz"}), "operator", false)
+		gomega.Expect(apierrors.IsConflict(err)).To(gomega.BeTrue())
+		configMap = genApplyConfigMap(name, map[string]string{"foo": "baz"})
+		gomega.Expect(k8sClient.ServerSideApply(context.Background(), configMap, "operator", true)).To(gomega.Succeed())
+		managers, err := FieldManagers(configMap, "data", "foo")
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		gomega.Expect(managers).To(gomega.Equal([]string{"operator"}))
+	})
+})
+
+var _ = Describe("FieldManagers", func() {
+	It("returns the managers of a field", func() {
+		name := "cm-" + rand.String(5)
+		configMap := genApplyConfigMap(name, map[string]string{"foo": "bar"})
+		gomega.Expect(k8sClient.ServerSideApply(context.Background(), configMap, "user", false)).To(gomega.Succeed())
+		defer k8sClient.Delete(context.Background(), configMap) // nolint:errcheck
+		configMap = genApplyConfigMap(name, map[string]string{"bar": "baz"})
+		gomega.Expect(k8sClient.ServerSideApply(context.Background(), configMap, "operator", false)).To(gomega.Succeed())
+		managers, err := FieldManagers(configMap, "data", "foo")
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		gomega.Expect(managers).To(gomega.Equal([]string{"user"}))
+		managers, err = FieldManagers(configMap, "data", "bar")
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		gomega.Expect(managers).To(gomega.Equal([]string{"operator"}))
+		managers, err = FieldManagers(configMap, "data")
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		gomega.Expect(managers).To(gomega.ConsistOf("user", "operator"))
+		managers, err = FieldManagers(configMap, "data", "doesnotexist")
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		gomega.Expect(managers).To(gomega.BeEmpty())
+	})
+	It("supports keys of associative lists", func() {
+		configMap := genApplyConfigMap("cm", nil)
+		configMap.ManagedFields = []metav1.ManagedFieldsEntry{{
+			Manager:    "operator",
+			Operation:  metav1.ManagedFieldsOperationApply,
+			FieldsType: "FieldsV1",
+			FieldsV1: &metav1.FieldsV1{
+				Raw: []byte(`{"f:spec":{"f:containers":{"k:{\"name\":\"nginx\"}":{".":{},"f:image":{}}}}}`),
+			},
+		}}
+		managers, err := FieldManagers(configMap, "spec", "containers", `k:{"name":"nginx"}`, "image")
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		gomega.Expect(managers).To(gomega.Equal([]string{"operator"}))
+		managers, err = FieldManagers(configMap, "spec", "containers", `k:{"name":"other"}`)
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		gomega.Expect(managers).To(gomega.BeEmpty())
+	})
+})
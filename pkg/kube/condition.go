@@ -0,0 +1,318 @@
+/*
+Copyright 2020 Testutil Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Condition is used by WaitUntil to wait for a specific state of an object.
+// WaitUntil keeps the subject up to date in place, so Check can simply
+// inspect the object returned by Subject.
+type Condition interface {
+	// Check returns true if the condition is met.
+	Check() bool
+	// Subject returns the object the condition is checked against.
+	Subject() runtime.Object
+	// Description returns a human-readable description of the condition.
+	Description() string
+}
+
+// TerminalCondition can be implemented by conditions, which are able to
+// detect that their subject reached a state it will not recover from. In
+// this case WaitUntil will return the error right away instead of waiting
+// for the context to be done.
+type TerminalCondition interface {
+	Condition
+	// Terminal returns an error, usually a *TerminalError, if the condition
+	// can not be met anymore.
+	Terminal() error
+}
+
+type conditionAdapter struct {
+	check       func() bool
+	terminal    func() error
+	subject     runtime.Object
+	description string
+}
+
+func (c conditionAdapter) Check() bool {
+	return c.check()
+}
+
+func (c conditionAdapter) Subject() runtime.Object {
+	return c.subject
+}
+
+func (c conditionAdapter) Description() string {
+	return c.description
+}
+
+func (c conditionAdapter) Terminal() error {
+	if c.terminal == nil {
+		return nil
+	}
+	return c.terminal()
+}
+
+// ConditionFunc creates a Condition for the provided subject, which is met
+// once check returns true. As the subject is updated in place, check can
+// refer to it directly, e.g.:
+//
+//	ConditionFunc(foo, "foo is ready", func() bool {
+//		return foo.Status.Ready
+//	})
+func ConditionFunc(subject runtime.Object, description string, check func() bool) Condition {
+	return conditionAdapter{
+		check:       check,
+		subject:     subject,
+		description: description,
+	}
+}
+
+// TerminalConditionFunc creates a Condition similar to ConditionFunc, but
+// additionally uses terminal to detect unrecoverable states of the subject.
+// If terminal returns an error, WaitUntil will stop waiting and return it.
+func TerminalConditionFunc(subject runtime.Object, description string, check func() bool, terminal func() error) Condition {
+	return conditionAdapter{
+		check:       check,
+		terminal:    terminal,
+		subject:     subject,
+		description: description,
+	}
+}
+
+// checkCondition returns whether the condition is met and if it is not,
+// whether it can still be met.
+func checkCondition(condition Condition) (bool, error) {
+	if condition.Check() {
+		return true, nil
+	}
+	return false, terminalError(condition)
+}
+
+func terminalError(condition Condition) error {
+	if terminal, ok := condition.(TerminalCondition); ok {
+		return terminal.Terminal()
+	}
+	return nil
+}
+
+// conditionGroup is implemented by conditions combining other conditions,
+// so WaitUntil is able to update the subjects of all of them.
+type conditionGroup interface {
+	conditions() []Condition
+}
+
+// conditionSubjects returns the distinct subjects of the condition and all
+// conditions it combines.
+func conditionSubjects(condition Condition) []runtime.Object {
+	group, ok := condition.(conditionGroup)
+	if !ok {
+		if condition.Subject() == nil {
+			return []runtime.Object{}
+		}
+		return []runtime.Object{condition.Subject()}
+	}
+	subjects := []runtime.Object{}
+	for _, c := range group.conditions() {
+		for _, subject := range conditionSubjects(c) {
+			if !containsObject(subjects, subject) {
+				subjects = append(subjects, subject)
+			}
+		}
+	}
+	return subjects
+}
+
+func containsObject(objs []runtime.Object, obj runtime.Object) bool {
+	for _, o := range objs {
+		if o == obj {
+			return true
+		}
+	}
+	return false
+}
+
+func describeConditions(conditions []Condition) string {
+	descriptions := make([]string, len(conditions))
+	for i, c := range conditions {
+		descriptions[i] = c.Description()
+	}
+	return strings.Join(descriptions, ", ")
+}
+
+type allCondition []Condition
+
+// All creates a Condition, which is met if all of the provided conditions
+// are met at the same time.
+func All(conditions ...Condition) Condition {
+	return allCondition(conditions)
+}
+
+func (c allCondition) Check() bool {
+	for _, condition := range c {
+		if !condition.Check() {
+			return false
+		}
+	}
+	return true
+}
+
+func (c allCondition) Subject() runtime.Object {
+	if len(c) == 0 {
+		return nil
+	}
+	return c[0].Subject()
+}
+
+func (c allCondition) Description() string {
+	return fmt.Sprintf("All(%s)", describeConditions(c))
+}
+
+func (c allCondition) Terminal() error {
+	for _, condition := range c {
+		if err := terminalError(condition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c allCondition) conditions() []Condition {
+	return c
+}
+
+type anyCondition []Condition
+
+// Any creates a Condition, which is met if at least one of the provided
+// conditions is met.
+func Any(conditions ...Condition) Condition {
+	return anyCondition(conditions)
+}
+
+func (c anyCondition) Check() bool {
+	for _, condition := range c {
+		if condition.Check() {
+			return true
+		}
+	}
+	return false
+}
+
+func (c anyCondition) Subject() runtime.Object {
+	if len(c) == 0 {
+		return nil
+	}
+	return c[0].Subject()
+}
+
+func (c anyCondition) Description() string {
+	return fmt.Sprintf("Any(%s)", describeConditions(c))
+}
+
+// Terminal will only return an error if none of the conditions can be met
+// anymore. The error of the first condition is returned.
+func (c anyCondition) Terminal() error {
+	var first error
+	for _, condition := range c {
+		err := terminalError(condition)
+		if err == nil {
+			return nil
+		}
+		if first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func (c anyCondition) conditions() []Condition {
+	return c
+}
+
+type notCondition struct {
+	condition Condition
+}
+
+// Not creates a Condition, which is met if the provided condition is not met.
+func Not(condition Condition) Condition {
+	return notCondition{condition: condition}
+}
+
+func (c notCondition) Check() bool {
+	return !c.condition.Check()
+}
+
+func (c notCondition) Subject() runtime.Object {
+	return c.condition.Subject()
+}
+
+func (c notCondition) Description() string {
+	return fmt.Sprintf("Not(%s)", c.condition.Description())
+}
+
+func (c notCondition) conditions() []Condition {
+	return []Condition{c.condition}
+}
+
+type stableCondition struct {
+	condition Condition
+	duration  time.Duration
+	since     time.Time
+}
+
+// StableFor creates a Condition, which is met once the provided condition
+// was met continuously for the specified duration. If the condition is not
+// met at any point in time, the duration starts over.
+func StableFor(condition Condition, duration time.Duration) Condition {
+	return &stableCondition{
+		condition: condition,
+		duration:  duration,
+	}
+}
+
+func (c *stableCondition) Check() bool {
+	if !c.condition.Check() {
+		c.since = time.Time{}
+		return false
+	}
+	if c.since.IsZero() {
+		c.since = time.Now()
+	}
+	return time.Since(c.since) >= c.duration
+}
+
+func (c *stableCondition) Subject() runtime.Object {
+	return c.condition.Subject()
+}
+
+func (c *stableCondition) Description() string {
+	return fmt.Sprintf("StableFor(%s, %s)", c.condition.Description(), c.duration)
+}
+
+func (c *stableCondition) Terminal() error {
+	return terminalError(c.condition)
+}
+
+func (c *stableCondition) conditions() []Condition {
+	return []Condition{c.condition}
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2020 Testutil Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+func constCondition(met bool) Condition {
+	return ConditionFunc(PodWithNamespacedName("a", "b"), "const", func() bool {
+		return met
+	})
+}
+
+var _ = Describe("Condition", func() {
+	It("combines conditions using All", func() {
+		gomega.Expect(All(constCondition(true), constCondition(true)).Check()).To(gomega.Equal(true))
+		gomega.Expect(All(constCondition(true), constCondition(false)).Check()).To(gomega.Equal(false))
+		gomega.Expect(All(constCondition(true)).Description()).To(gomega.Equal("All(const)"))
+	})
+	It("combines conditions using Any", func() {
+		gomega.Expect(Any(constCondition(false), constCondition(true)).Check()).To(gomega.Equal(true))
+		gomega.Expect(Any(constCondition(false), constCondition(false)).Check()).To(gomega.Equal(false))
+	})
+	It("negates conditions using Not", func() {
+		gomega.Expect(Not(constCondition(false)).Check()).To(gomega.Equal(true))
+		gomega.Expect(Not(constCondition(true)).Check()).To(gomega.Equal(false))
+		gomega.Expect(Not(constCondition(true)).Description()).To(gomega.Equal("Not(const)"))
+	})
+	It("requires conditions to be stable", func() {
+		met := true
+		pod := PodWithNamespacedName("a", "b")
+		condition := StableFor(ConditionFunc(pod, "toggle", func() bool {
+			return met
+		}), 100*time.Millisecond)
+		gomega.Expect(condition.Check()).To(gomega.Equal(false))
+		time.Sleep(150 * time.Millisecond)
+		met = false
+		gomega.Expect(condition.Check()).To(gomega.Equal(false))
+		met = true
+		gomega.Expect(condition.Check()).To(gomega.Equal(false))
+		time.Sleep(150 * time.Millisecond)
+		gomega.Expect(condition.Check()).To(gomega.Equal(true))
+	})
+	It("collects distinct subjects", func() {
+		a := PodWithNamespacedName("a", "a")
+		b := PodWithNamespacedName("a", "b")
+		condition := All(PodIsReady(a), Not(PodIsReady(b)), StableFor(PodIsReady(a), time.Second))
+		gomega.Expect(conditionSubjects(condition)).To(gomega.HaveLen(2))
+	})
+	It("can be used with WaitUntil", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		deployment := DeploymentWithNamespacedName(nginxRelease.Namespace, nginxRelease.Name+"-nginx")
+		pod := mustGetReadyNginxPod(nginxRelease)
+		gomega.Expect(k8sClient.WaitUntil(ctx,
+			All(DeploymentIsReady(deployment), DeploymentIsUpdated(deployment)),
+			Any(PodIsReady(pod), Not(PodIsReady(pod))),
+			StableFor(All(DeploymentIsReady(deployment), PodIsReady(pod)), 2*time.Second),
+		)).To(gomega.Succeed())
+	})
+})
@@ -0,0 +1,203 @@
+/*
+Copyright 2020 Testutil Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/kubism/testutil/pkg/fs"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// CopyToPod copies the local file or directory to the remote path in the
+// container of the pod similar to `kubectl cp`. The files are streamed as tar
+// archive, so the container requires `sh` and `tar`. File modes are kept.
+func (c *Client) CopyToPod(ctx context.Context, pod *corev1.Pod, container, localPath, remotePath string) error {
+	if _, err := os.Stat(localPath); err != nil {
+		return err
+	}
+	remotePath = path.Clean(remotePath)
+	reader, writer := io.Pipe()
+	defer reader.Close()
+	go func() {
+		writer.CloseWithError(writeTar(writer, localPath, path.Base(remotePath)))
+	}()
+	cmd := []string{"sh", "-c", `mkdir -p "$0" && tar -xf - -C "$0"`, path.Dir(remotePath)}
+	result, err := c.Exec(ctx, pod, container, cmd, ExecWithStdin(reader))
+	if err != nil {
+		return err
+	}
+	if result.ExitCode != 0 {
+		return &ExecError{result}
+	}
+	return nil
+}
+
+// CopyFromPod copies the remote file or directory from the container of the
+// pod to the local path similar to `kubectl cp`. The files are streamed as
+// tar archive, so the container requires `tar`. File modes are kept, but
+// symbolic links are skipped.
+func (c *Client) CopyFromPod(ctx context.Context, pod *corev1.Pod, container, remotePath, localPath string) error {
+	remotePath = path.Clean(remotePath)
+	reader, writer := io.Pipe()
+	tarErrCh := make(chan error, 1)
+	go func() {
+		err := readTar(reader, path.Base(remotePath), localPath)
+		if err != nil {
+			reader.CloseWithError(err) // aborts the command
+		} else {
+			// the command might still write the padding of the archive
+			io.Copy(ioutil.Discard, reader) // nolint:errcheck
+		}
+		tarErrCh <- err
+	}()
+	cmd := []string{"tar", "-cf", "-", "-C", path.Dir(remotePath), path.Base(remotePath)}
+	result, err := c.Exec(ctx, pod, container, cmd, execWithStdout(writer))
+	writer.CloseWithError(err)
+	tarErr := <-tarErrCh
+	if err != nil {
+		if tarErr != nil {
+			return tarErr
+		}
+		return err
+	}
+	if result.ExitCode != 0 {
+		return &ExecError{result}
+	}
+	return tarErr
+}
+
+// CopyFromPodToTempDir copies the remote file or directory from the container
+// of the pod into a new temporary directory using CopyFromPod. The copy will
+// have the same base name as the remote path. Make sure to always call Close
+// on the returned directory.
+func (c *Client) CopyFromPodToTempDir(ctx context.Context, pod *corev1.Pod, container, remotePath string) (*fs.TempDir, error) {
+	dir, err := fs.NewTempDir()
+	if err != nil {
+		return nil, err
+	}
+	localPath := filepath.Join(dir.Path, path.Base(path.Clean(remotePath)))
+	if err := c.CopyFromPod(ctx, pod, container, remotePath, localPath); err != nil {
+		dir.Close()
+		return nil, err
+	}
+	return dir, nil
+}
+
+// writeTar writes the local file or directory as tar archive, where all
+// entries are prefixed by name instead of the base name of the local path.
+func writeTar(w io.Writer, localPath, name string) error {
+	tw := tar.NewWriter(w)
+	err := filepath.Walk(localPath, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(localPath, file)
+		if err != nil {
+			return err
+		}
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(file); err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = path.Join(name, filepath.ToSlash(rel))
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// readTar extracts the tar archive to the local path, where the prefix name
+// of all entries is replaced by the local path. Entries escaping the local
+// path are rejected.
+func readTar(r io.Reader, name, localPath string) error {
+	localPath = filepath.Clean(localPath)
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		entry := path.Clean(header.Name)
+		if entry != name && !strings.HasPrefix(entry, name+"/") {
+			return fmt.Errorf("unexpected tar entry %s", header.Name)
+		}
+		target := filepath.Join(localPath, filepath.FromSlash(strings.TrimPrefix(entry, name)))
+		if target != localPath && !strings.HasPrefix(target, localPath+string(filepath.Separator)) {
+			return fmt.Errorf("tar entry %s is outside of target path", header.Name)
+		}
+		mode := os.FileMode(header.Mode).Perm()
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, mode); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeFileFromReader(target, mode, tr); err != nil {
+				return err
+			}
+		default:
+			continue // symbolic links and special files are skipped
+		}
+	}
+}
+
+func writeFileFromReader(file string, mode os.FileMode, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := io.Copy(f, r); err != nil {
+		return err
+	}
+	return f.Chmod(mode) // the umask might have altered the mode
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2020 Testutil Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/kubism/testutil/pkg/fs"
+	"github.com/kubism/testutil/pkg/rand"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+var _ = Describe("Copy", func() {
+	It("copies a file to and from a pod", func() {
+		pod := mustGetReadyNginxPod(nginxRelease)
+		tf, err := fs.NewTempFile([]byte("hello"))
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		defer tf.Close()
+		remotePath := "/tmp/" + rand.String(5) + "/hello.txt"
+		gomega.Expect(k8sClient.CopyToPod(context.Background(), pod, "", tf.Path, remotePath)).To(gomega.Succeed())
+		out, err := k8sClient.ExecString(context.Background(), pod, "", []string{"cat", remotePath})
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		gomega.Expect(out).To(gomega.Equal("hello"))
+		td, err := k8sClient.CopyFromPodToTempDir(context.Background(), pod, "", remotePath)
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		defer td.Close()
+		content, err := ioutil.ReadFile(filepath.Join(td.Path, "hello.txt"))
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		gomega.Expect(string(content)).To(gomega.Equal("hello"))
+	})
+	It("copies a directory and keeps file modes", func() {
+		pod := mustGetReadyNginxPod(nginxRelease)
+		td, err := fs.NewTempDir()
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		defer td.Close()
+		gomega.Expect(os.MkdirAll(filepath.Join(td.Path, "src", "bin"), 0755)).To(gomega.Succeed())
+		gomega.Expect(ioutil.WriteFile(filepath.Join(td.Path, "src", "bin", "run.sh"), []byte("#!/bin/sh\necho ok\n"), 0755)).To(gomega.Succeed())
+		remotePath := "/tmp/" + rand.String(5)
+		gomega.Expect(k8sClient.CopyToPod(context.Background(), pod, "", filepath.Join(td.Path, "src"), remotePath)).To(gomega.Succeed())
+		out, err := k8sClient.ExecString(context.Background(), pod, "", []string{remotePath + "/bin/run.sh"})
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		gomega.Expect(out).To(gomega.Equal("ok\n"))
+		localPath := filepath.Join(td.Path, "dst")
+		gomega.Expect(k8sClient.CopyFromPod(context.Background(), pod, "", remotePath, localPath)).To(gomega.Succeed())
+		info, err := os.Stat(filepath.Join(localPath, "bin", "run.sh"))
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		gomega.Expect(info.Mode().Perm()).To(gomega.Equal(os.FileMode(0755)))
+	})
+	It("fails for non-existing remote path", func() {
+		pod := mustGetReadyNginxPod(nginxRelease)
+		td, err := k8sClient.CopyFromPodToTempDir(context.Background(), pod, "", "/doesnotexist")
+		gomega.Expect(err).To(gomega.HaveOccurred())
+		gomega.Expect(td).To(gomega.BeNil())
+	})
+	It("fails for non-existing local path", func() {
+		pod := mustGetReadyNginxPod(nginxRelease)
+		gomega.Expect(k8sClient.CopyToPod(context.Background(), pod, "", "/doesnotexist", "/tmp/x")).ToNot(gomega.Succeed())
+	})
+})
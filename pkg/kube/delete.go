@@ -0,0 +1,169 @@
+/*
+Copyright 2020 Testutil Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// removeFinalizersPatch is a merge patch removing all finalizers of an object.
+var removeFinalizersPatch = client.RawPatch(types.MergePatchType, []byte(`{"metadata":{"finalizers":null}}`))
+
+type waitUntilDeletedOptions struct {
+	Force       bool
+	GracePeriod time.Duration
+}
+
+// WaitUntilDeletedOption interface is implemented by all possible options to
+// wait until an object is deleted.
+type WaitUntilDeletedOption interface {
+	apply(*waitUntilDeletedOptions)
+}
+
+type waitUntilDeletedOptionAdapter func(*waitUntilDeletedOptions)
+
+func (c waitUntilDeletedOptionAdapter) apply(o *waitUntilDeletedOptions) {
+	c(o)
+}
+
+// WaitUntilDeletedWithForce will force the deletion of the object if it still
+// exists after the grace period. The object is deleted if it is not already
+// being deleted and all of its finalizers are removed.
+func WaitUntilDeletedWithForce(gracePeriod time.Duration) WaitUntilDeletedOption {
+	return waitUntilDeletedOptionAdapter(func(o *waitUntilDeletedOptions) {
+		o.Force = true
+		o.GracePeriod = gracePeriod
+	})
+}
+
+// WaitUntilDeleted blocks until the object does not exist anymore, which is
+// the case if it can not be found or was replaced by an object with a
+// different UID. It does not delete the object by itself unless forced to.
+// The returned slice contains the finalizers, which were blocking the
+// deletion and were removed by force.
+//
+// If the context is done before the object was deleted, a *WaitError is
+// returned, which also lists the finalizers still present.
+func (c *Client) WaitUntilDeleted(ctx context.Context, obj runtime.Object, opts ...WaitUntilDeletedOption) ([]string, error) {
+	options := waitUntilDeletedOptions{}
+	for _, opt := range opts {
+		opt.apply(&options)
+	}
+	objectKey, err := client.ObjectKeyFromObject(obj)
+	if err != nil {
+		return nil, err
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, err
+	}
+	uid := accessor.GetUID()
+	current := obj.DeepCopyObject()
+	currentAccessor, err := meta.Accessor(current)
+	if err != nil {
+		return nil, err
+	}
+	forceAt := time.Now().Add(options.GracePeriod)
+	removed := []string{}
+	backoff := c.pollBackoff()
+	for {
+		err := c.Get(ctx, objectKey, current)
+		if apierrors.IsNotFound(err) {
+			return removed, nil
+		} else if err != nil && ctx.Err() == nil {
+			return removed, err
+		} else if err == nil {
+			if uid != "" && currentAccessor.GetUID() != uid {
+				return removed, nil // a new object was created in the meantime
+			}
+			if options.Force && !time.Now().Before(forceAt) {
+				finalizers, err := c.forceDelete(ctx, current)
+				if err != nil && ctx.Err() == nil {
+					return removed, err
+				}
+				for _, finalizer := range finalizers {
+					if !containsString(removed, finalizer) {
+						removed = append(removed, finalizer)
+					}
+				}
+			}
+		}
+		timer := time.NewTimer(backoff.Step())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return removed, c.newWaitError(objectIsDeleted(current), ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// forceDelete deletes the object if it is not already being deleted and
+// removes all of its finalizers. The finalizers are only returned if they
+// were actually removed.
+func (c *Client) forceDelete(ctx context.Context, obj runtime.Object) ([]string, error) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, err
+	}
+	if accessor.GetDeletionTimestamp() == nil {
+		if err := c.Delete(ctx, obj); err != nil && !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+	}
+	finalizers := accessor.GetFinalizers()
+	if len(finalizers) == 0 {
+		return nil, nil
+	}
+	if err := c.Patch(ctx, obj, removeFinalizersPatch); apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return finalizers, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// objectIsDeleted is only used to describe the state of an object, which was
+// not deleted in time.
+func objectIsDeleted(obj runtime.Object) Condition {
+	description := "ObjectIsDeleted"
+	if accessor, err := meta.Accessor(obj); err == nil && len(accessor.GetFinalizers()) > 0 {
+		description = fmt.Sprintf("%s (blocked by finalizers: %s)", description,
+			strings.Join(accessor.GetFinalizers(), ", "))
+	}
+	return ConditionFunc(obj, description, func() bool {
+		return false
+	})
+}
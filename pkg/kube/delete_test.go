@@ -0,0 +1,83 @@
+/*
+Copyright 2020 Testutil Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/kubism/testutil/pkg/rand"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+const testFinalizer = "testutil.kubism.io/block"
+
+func mustCreateConfigMap(finalizers ...string) *corev1.ConfigMap {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:  "default",
+			Name:       "cm-" + rand.String(5),
+			Finalizers: finalizers,
+		},
+		Data: map[string]string{"foo": "bar"},
+	}
+	gomega.Expect(k8sClient.Create(context.Background(), configMap)).To(gomega.Succeed())
+	return configMap
+}
+
+var _ = Describe("WaitUntilDeleted", func() {
+	It("waits until object is deleted", func() {
+		configMap := mustCreateConfigMap()
+		gomega.Expect(k8sClient.Delete(context.Background(), configMap)).To(gomega.Succeed())
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		removed, err := k8sClient.WaitUntilDeleted(ctx, configMap)
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		gomega.Expect(removed).To(gomega.BeEmpty())
+	})
+	It("reports blocking finalizers on timeout", func() {
+		configMap := mustCreateConfigMap(testFinalizer)
+		defer func() {
+			_, _ = k8sClient.WaitUntilDeleted(context.Background(), configMap, WaitUntilDeletedWithForce(0))
+		}()
+		gomega.Expect(k8sClient.Delete(context.Background(), configMap)).To(gomega.Succeed())
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		_, err := k8sClient.WaitUntilDeleted(ctx, configMap)
+		var waitErr *WaitError
+		gomega.Expect(errors.As(err, &waitErr)).To(gomega.Equal(true))
+		gomega.Expect(waitErr.Condition).To(gomega.ContainSubstring(testFinalizer))
+	})
+	It("removes blocking finalizers by force", func() {
+		configMap := mustCreateConfigMap(testFinalizer)
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		removed, err := k8sClient.WaitUntilDeleted(ctx, configMap, WaitUntilDeletedWithForce(time.Second))
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		gomega.Expect(removed).To(gomega.ConsistOf(testFinalizer))
+	})
+	It("fails for invalid object", func() {
+		_, err := k8sClient.WaitUntilDeleted(context.Background(), nil)
+		gomega.Expect(err).To(gomega.HaveOccurred())
+	})
+})
@@ -0,0 +1,310 @@
+/*
+Copyright 2020 Testutil Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/tools/portforward"
+)
+
+// PodDialer opens connections to ports of a pod through a single port-forward
+// session without binding any local port. Make sure to always call Close
+// once it is not required anymore.
+type PodDialer struct {
+	pod       *corev1.Pod
+	conn      httpstream.Connection
+	mu        sync.Mutex
+	requestID int
+}
+
+// NewPodDialer establishes a port-forward session to the pod, which can be
+// used to dial any of its ports.
+func (c *Client) NewPodDialer(pod *corev1.Pod) (*PodDialer, error) {
+	dialer, err := newPortForwardDialer(c.restConfig, c.Clientset.CoreV1().RESTClient(), pod)
+	if err != nil {
+		return nil, err
+	}
+	conn, _, err := dialer.Dial(portforward.PortForwardProtocolV1Name)
+	if err != nil {
+		return nil, fmt.Errorf("error upgrading connection: %w", err)
+	}
+	return &PodDialer{pod: pod, conn: conn}, nil
+}
+
+// DialContext connects to the port of the address within the pod, so it can
+// be used as `DialContext` of a net.Dialer or http.Transport. The host of the
+// address is ignored, as the connection is always tunnelled to the pod.
+func (d *PodDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	if network != "tcp" && network != "tcp4" && network != "tcp6" {
+		return nil, fmt.Errorf("unsupported network %s", network)
+	}
+	_, rawPort, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.Atoi(rawPort)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port %s: %w", rawPort, err)
+	}
+	return d.DialPort(ctx, port)
+}
+
+// DialPort connects to the port within the pod.
+func (d *PodDialer) DialPort(ctx context.Context, port int) (net.Conn, error) {
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+	resultCh := make(chan result, 1)
+	go func() {
+		conn, err := d.dial(port)
+		resultCh <- result{conn, err}
+	}()
+	select {
+	case <-ctx.Done():
+		go func() { // the streams can not be interrupted, so close them later
+			if r := <-resultCh; r.conn != nil {
+				r.conn.Close()
+			}
+		}()
+		return nil, ctx.Err()
+	case r := <-resultCh:
+		return r.conn, r.err
+	}
+}
+
+func (d *PodDialer) dial(port int) (net.Conn, error) {
+	d.mu.Lock()
+	d.requestID++
+	requestID := d.requestID
+	d.mu.Unlock()
+	headers := http.Header{}
+	headers.Set(corev1.StreamType, corev1.StreamTypeError)
+	headers.Set(corev1.PortHeader, strconv.Itoa(port))
+	headers.Set(corev1.PortForwardRequestIDHeader, strconv.Itoa(requestID))
+	errorStream, err := d.conn.CreateStream(headers)
+	if err != nil {
+		return nil, fmt.Errorf("error creating error stream for port %d: %w", port, err)
+	}
+	errorStream.Close() // we're not writing to this stream
+	headers.Set(corev1.StreamType, corev1.StreamTypeData)
+	dataStream, err := d.conn.CreateStream(headers)
+	if err != nil {
+		errorStream.Reset() // nolint:errcheck
+		return nil, fmt.Errorf("error creating data stream for port %d: %w", port, err)
+	}
+	conn := &podConn{
+		Stream:      dataStream,
+		errorStream: errorStream,
+		errCh:       make(chan error, 1),
+		addr:        podAddr{NamespacedName(d.pod).String(), port},
+		conn:        d.conn,
+	}
+	go func() {
+		message, err := ioutil.ReadAll(errorStream)
+		if err != nil {
+			conn.errCh <- fmt.Errorf("error reading from error stream for port %d: %w", port, err)
+		} else if len(message) > 0 {
+			conn.errCh <- fmt.Errorf("an error occurred forwarding port %d: %s", port, message)
+		}
+		close(conn.errCh)
+	}()
+	return conn, nil
+}
+
+// HTTPClient returns a http.Client sending all requests to the pod. The port
+// is taken from the URL, e.g. `http://localhost:8080/`.
+func (d *PodDialer) HTTPClient() *http.Client {
+	return &http.Client{Transport: &http.Transport{
+		DialContext: d.DialContext,
+	}}
+}
+
+// ContextDialer returns a dial function, which can be passed to
+// `grpc.WithContextDialer` to connect a gRPC client to the pod.
+func (d *PodDialer) ContextDialer() func(ctx context.Context, address string) (net.Conn, error) {
+	return func(ctx context.Context, address string) (net.Conn, error) {
+		return d.DialContext(ctx, "tcp", address)
+	}
+}
+
+// Done returns a channel, which is closed once the session was closed or the
+// connection to the pod was lost.
+func (d *PodDialer) Done() <-chan bool {
+	return d.conn.CloseChan()
+}
+
+// Close closes the session including all of its connections.
+func (d *PodDialer) Close() error {
+	return d.conn.Close()
+}
+
+// podConn is a net.Conn tunnelled to a port of a pod.
+type podConn struct {
+	httpstream.Stream
+	errorStream httpstream.Stream
+	errCh       chan error
+	addr        podAddr
+	conn        httpstream.Connection
+
+	mu         sync.Mutex
+	readTimer  *time.Timer
+	writeTimer *time.Timer
+	timedOut   bool
+}
+
+var _ net.Conn = &podConn{}
+
+// Read reports errors of the remote side, e.g. if nothing is listening on
+// the port, once the data stream is exhausted.
+func (c *podConn) Read(b []byte) (int, error) {
+	n, err := c.Stream.Read(b)
+	if err != nil && c.deadlineExceeded() {
+		return n, errDeadlineExceeded
+	}
+	if err == io.EOF {
+		if remoteErr := <-c.errCh; remoteErr != nil {
+			return n, remoteErr
+		}
+	}
+	return n, err
+}
+
+func (c *podConn) Write(b []byte) (int, error) {
+	n, err := c.Stream.Write(b)
+	if err != nil && c.deadlineExceeded() {
+		return n, errDeadlineExceeded
+	}
+	return n, err
+}
+
+// Close resets the streams and removes them from the session, if supported
+// by the connection.
+func (c *podConn) Close() error {
+	c.mu.Lock()
+	stopTimer(c.readTimer)
+	stopTimer(c.writeTimer)
+	c.mu.Unlock()
+	c.errorStream.Reset() // nolint:errcheck
+	err := c.Stream.Reset()
+	if remover, ok := c.conn.(streamRemover); ok {
+		remover.RemoveStreams(c.errorStream, c.Stream)
+	}
+	return err
+}
+
+func (c *podConn) LocalAddr() net.Addr {
+	return c.addr
+}
+
+func (c *podConn) RemoteAddr() net.Addr {
+	return c.addr
+}
+
+// SetDeadline sets the read and write deadlines. The underlying streams can
+// not be interrupted, so the connection is closed once a deadline is
+// exceeded and pending as well as future calls fail with a timeout error.
+func (c *podConn) SetDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readTimer = c.resetTimer(c.readTimer, t)
+	c.writeTimer = c.resetTimer(c.writeTimer, t)
+	return nil
+}
+
+func (c *podConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readTimer = c.resetTimer(c.readTimer, t)
+	return nil
+}
+
+func (c *podConn) SetWriteDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.writeTimer = c.resetTimer(c.writeTimer, t)
+	return nil
+}
+
+// resetTimer replaces timer with a new one closing the connection at t. A
+// zero t disables the deadline.
+func (c *podConn) resetTimer(timer *time.Timer, t time.Time) *time.Timer {
+	stopTimer(timer)
+	if t.IsZero() {
+		return nil
+	}
+	return time.AfterFunc(time.Until(t), c.expire)
+}
+
+func (c *podConn) expire() {
+	c.mu.Lock()
+	c.timedOut = true
+	c.mu.Unlock()
+	c.Close() // nolint:errcheck
+}
+
+func (c *podConn) deadlineExceeded() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.timedOut
+}
+
+func stopTimer(timer *time.Timer) {
+	if timer != nil {
+		timer.Stop()
+	}
+}
+
+// streamRemover is implemented by connections, which are able to forget
+// streams once they are closed. Older versions of httpstream keep all
+// streams until the connection is closed.
+type streamRemover interface {
+	RemoveStreams(streams ...httpstream.Stream)
+}
+
+// errDeadlineExceeded is returned by podConn once a deadline was exceeded.
+var errDeadlineExceeded error = timeoutError{}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+type podAddr struct {
+	pod  string
+	port int
+}
+
+func (a podAddr) Network() string {
+	return "portforward"
+}
+
+func (a podAddr) String() string {
+	return net.JoinHostPort(a.pod, strconv.Itoa(a.port))
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2020 Testutil Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+var _ = Describe("PodDialer", func() {
+	It("can be used by http.Client", func() {
+		pod := mustGetReadyNginxPod(nginxRelease)
+		dialer, err := k8sClient.NewPodDialer(pod)
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		defer dialer.Close()
+		httpClient := dialer.HTTPClient()
+		for i := 0; i < 3; i++ {
+			resp, err := httpClient.Get("http://nginx:8080/")
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(resp.StatusCode).To(gomega.Equal(http.StatusOK))
+			_, err = ioutil.ReadAll(resp.Body)
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			resp.Body.Close()
+		}
+	})
+	It("reports errors of closed ports", func() {
+		pod := mustGetReadyNginxPod(nginxRelease)
+		dialer, err := k8sClient.NewPodDialer(pod)
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		defer dialer.Close()
+		conn, err := dialer.DialPort(context.Background(), 12345)
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		defer conn.Close()
+		_, err = ioutil.ReadAll(conn)
+		gomega.Expect(err).To(gomega.HaveOccurred())
+	})
+	It("times out reads once the deadline is exceeded", func() {
+		pod := mustGetReadyNginxPod(nginxRelease)
+		dialer, err := k8sClient.NewPodDialer(pod)
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		defer dialer.Close()
+		conn, err := dialer.DialPort(context.Background(), 8080)
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		defer conn.Close()
+		gomega.Expect(conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))).To(gomega.Succeed())
+		_, err = conn.Read(make([]byte, 1)) // nginx waits for the request
+		var netErr net.Error
+		gomega.Expect(errors.As(err, &netErr)).To(gomega.BeTrue())
+		gomega.Expect(netErr.Timeout()).To(gomega.BeTrue())
+	})
+	It("fails for unsupported network", func() {
+		pod := mustGetReadyNginxPod(nginxRelease)
+		dialer, err := k8sClient.NewPodDialer(pod)
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		defer dialer.Close()
+		_, err = dialer.DialContext(context.Background(), "udp", "nginx:53")
+		gomega.Expect(err).To(gomega.HaveOccurred())
+	})
+	It("fails for non-existing pod", func() {
+		var pod corev1.Pod
+		pod.ObjectMeta.Namespace = "default"
+		pod.ObjectMeta.Name = "doesnotexist"
+		dialer, err := k8sClient.NewPodDialer(&pod)
+		gomega.Expect(err).To(gomega.HaveOccurred())
+		gomega.Expect(dialer).To(gomega.BeNil())
+	})
+})
@@ -0,0 +1,257 @@
+/*
+Copyright 2020 Testutil Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/reference"
+)
+
+// eventsPageSize is the number of events retrieved per request.
+const eventsPageSize = 250
+
+type eventsOptions struct {
+	Reason string
+	Type   string
+	Since  time.Time
+}
+
+// EventsOption interface is implemented by all possible options to retrieve
+// events of an object.
+type EventsOption interface {
+	apply(*eventsOptions)
+}
+
+type eventsOptionAdapter func(*eventsOptions)
+
+func (c eventsOptionAdapter) apply(o *eventsOptions) {
+	c(o)
+}
+
+// EventsWithReason will only retrieve events with the reason, e.g. `Created`.
+func EventsWithReason(reason string) EventsOption {
+	return eventsOptionAdapter(func(o *eventsOptions) {
+		o.Reason = reason
+	})
+}
+
+// EventsWithType will only retrieve events of the type, which is either
+// corev1.EventTypeNormal or corev1.EventTypeWarning.
+func EventsWithType(eventType string) EventsOption {
+	return eventsOptionAdapter(func(o *eventsOptions) {
+		o.Type = eventType
+	})
+}
+
+// EventsWithSinceTime will only retrieve events, which were last observed at
+// or after the time.
+func EventsWithSinceTime(since time.Time) EventsOption {
+	return eventsOptionAdapter(func(o *eventsOptions) {
+		o.Since = since
+	})
+}
+
+// Events retrieves the events regarding the object. The events are filtered
+// by the API server using field selectors and retrieved in pages. Events of
+// cluster-scoped objects, e.g. Nodes, are searched in all namespaces.
+//
+// Events recorded using the events.k8s.io API are included as well, as both
+// APIs share the same storage. Use EventLastObserved to order them, as
+// those events usually do not set the last timestamp.
+func (c *Client) Events(ctx context.Context, obj runtime.Object, opts ...EventsOption) ([]corev1.Event, error) {
+	options := eventsOptions{}
+	for _, opt := range opts {
+		opt.apply(&options)
+	}
+	namespace, listOptions, err := c.eventsListOptions(obj, options)
+	if err != nil {
+		return nil, err
+	}
+	events, _, err := c.listEvents(ctx, namespace, listOptions, options)
+	return events, err
+}
+
+// WaitForEvent blocks until an event with the reason regarding the object
+// exists and returns it. Already existing events are considered as well
+// unless limited by EventsWithSinceTime. New events are watched for, or the
+// API server is polled if the watch can not be used.
+//
+// If the context is done before such an event appeared, a *WaitError is
+// returned.
+func (c *Client) WaitForEvent(ctx context.Context, obj runtime.Object, reason string, opts ...EventsOption) (*corev1.Event, error) {
+	options := eventsOptions{}
+	for _, opt := range append(opts, EventsWithReason(reason)) {
+		opt.apply(&options)
+	}
+	namespace, listOptions, err := c.eventsListOptions(obj, options)
+	if err != nil {
+		return nil, err
+	}
+	backoff := c.pollBackoff()
+	for {
+		events, resourceVersion, err := c.listEvents(ctx, namespace, listOptions, options)
+		if err != nil && ctx.Err() == nil {
+			return nil, err
+		} else if len(events) > 0 {
+			return latestEvent(events), nil
+		}
+		if err == nil && !c.options.PollingOnly {
+			watchOptions := listOptions
+			watchOptions.ResourceVersion = resourceVersion
+			if event := c.watchEvent(ctx, namespace, watchOptions, options); event != nil {
+				return event, nil
+			}
+		}
+		timer := time.NewTimer(backoff.Step())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, c.newWaitError(ConditionFunc(obj, fmt.Sprintf("EventWithReason(%s)", reason), func() bool {
+				return false
+			}), ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// eventsListOptions computes the namespace and field selectors to list the
+// events of the object.
+func (c *Client) eventsListOptions(obj runtime.Object, options eventsOptions) (string, metav1.ListOptions, error) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return "", metav1.ListOptions{}, err
+	}
+	ref, err := reference.GetReference(c.scheme, obj)
+	if err != nil {
+		return "", metav1.ListOptions{}, err
+	}
+	selector := fields.Set{
+		"involvedObject.kind": ref.Kind,
+		"involvedObject.name": ref.Name,
+	}
+	// Kinds of different API groups might share the same name.
+	if ref.APIVersion != "" {
+		selector["involvedObject.apiVersion"] = ref.APIVersion
+	}
+	// The kubelet records events of nodes using the name as uid, so the uid
+	// is only reliable for namespaced objects.
+	if accessor.GetNamespace() != "" && accessor.GetUID() != "" {
+		selector["involvedObject.uid"] = string(accessor.GetUID())
+	}
+	if options.Reason != "" {
+		selector["reason"] = options.Reason
+	}
+	if options.Type != "" {
+		selector["type"] = options.Type
+	}
+	return accessor.GetNamespace(), metav1.ListOptions{
+		FieldSelector: selector.AsSelector().String(),
+		Limit:         eventsPageSize,
+	}, nil
+}
+
+// listEvents retrieves all pages of events and returns them with the
+// resource version of the list.
+func (c *Client) listEvents(ctx context.Context, namespace string, listOptions metav1.ListOptions, options eventsOptions) ([]corev1.Event, string, error) {
+	events := []corev1.Event{}
+	for {
+		list, err := c.Clientset.CoreV1().Events(namespace).List(ctx, listOptions)
+		if err != nil {
+			return nil, "", err
+		}
+		events = append(events, filterEvents(list.Items, options)...)
+		if list.Continue == "" {
+			return events, list.ResourceVersion, nil
+		}
+		listOptions.Continue = list.Continue
+	}
+}
+
+// watchEvent watches for an event matching the options. It returns nil if the
+// watch could not be used or was closed before such an event appeared.
+func (c *Client) watchEvent(ctx context.Context, namespace string, listOptions metav1.ListOptions, options eventsOptions) *corev1.Event {
+	listOptions.Limit = 0
+	watcher, err := c.Clientset.CoreV1().Events(namespace).Watch(ctx, listOptions)
+	if err != nil {
+		return nil
+	}
+	defer watcher.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case e, ok := <-watcher.ResultChan():
+			if !ok || e.Type == watch.Error {
+				return nil
+			}
+			event, ok := e.Object.(*corev1.Event)
+			if !ok || e.Type == watch.Deleted {
+				continue
+			}
+			if events := filterEvents([]corev1.Event{*event}, options); len(events) > 0 {
+				return &events[0]
+			}
+		}
+	}
+}
+
+// filterEvents applies the options, which can not be handled by field
+// selectors.
+func filterEvents(in []corev1.Event, options eventsOptions) []corev1.Event {
+	out := []corev1.Event{}
+	for _, e := range in {
+		if !options.Since.IsZero() && EventLastObserved(&e).Before(options.Since) {
+			continue
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+func latestEvent(events []corev1.Event) *corev1.Event {
+	latest := &events[0]
+	for i := range events {
+		if EventLastObserved(&events[i]).After(EventLastObserved(latest)) {
+			latest = &events[i]
+		}
+	}
+	return latest
+}
+
+// EventLastObserved returns the time the event was last observed. Events
+// recorded using the events.k8s.io API use the event time and series instead
+// of the timestamps.
+func EventLastObserved(event *corev1.Event) time.Time {
+	switch {
+	case event.Series != nil && !event.Series.LastObservedTime.IsZero():
+		return event.Series.LastObservedTime.Time
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	}
+	return event.FirstTimestamp.Time
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2020 Testutil Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+var _ = Describe("Events", func() {
+	It("can get events for existing pod", func() {
+		pod := mustGetReadyNginxPod(nginxRelease)
+		events, err := k8sClient.Events(context.Background(), pod)
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		gomega.Expect(len(events)).To(gomega.BeNumerically(">", 0))
+		for _, event := range events {
+			gomega.Expect(event.InvolvedObject.UID).To(gomega.Equal(pod.UID))
+		}
+	})
+	It("can filter by reason and type", func() {
+		pod := mustGetReadyNginxPod(nginxRelease)
+		events, err := k8sClient.Events(context.Background(), pod,
+			EventsWithReason("Scheduled"), EventsWithType(corev1.EventTypeNormal))
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		gomega.Expect(events).To(gomega.HaveLen(1))
+		gomega.Expect(events[0].Reason).To(gomega.Equal("Scheduled"))
+		events, err = k8sClient.Events(context.Background(), pod,
+			EventsWithSinceTime(time.Now().Add(time.Hour)))
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		gomega.Expect(events).To(gomega.BeEmpty())
+	})
+	It("can get events for cluster-scoped objects", func() {
+		nodeList := &corev1.NodeList{}
+		gomega.Expect(k8sClient.List(context.Background(), nodeList)).To(gomega.Succeed())
+		gomega.Expect(nodeList.Items).ToNot(gomega.BeEmpty())
+		_, err := k8sClient.Events(context.Background(), &nodeList.Items[0])
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+	})
+})
+
+var _ = Describe("WaitForEvent", func() {
+	It("waits until event appears", func() {
+		pod := mustCreateLoggingPod("sleep 3600")
+		defer k8sClient.Delete(context.Background(), pod) // nolint:errcheck
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		event, err := k8sClient.WaitForEvent(ctx, pod, "Started")
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		gomega.Expect(event.Reason).To(gomega.Equal("Started"))
+		gomega.Expect(event.InvolvedObject.UID).To(gomega.Equal(pod.UID))
+	})
+	It("returns WaitError on timeout", func() {
+		pod := mustGetReadyNginxPod(nginxRelease)
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		_, err := k8sClient.WaitForEvent(ctx, pod, "NeverRecorded")
+		var waitErr *WaitError
+		gomega.Expect(errors.As(err, &waitErr)).To(gomega.Equal(true))
+		gomega.Expect(waitErr.Events).ToNot(gomega.BeEmpty())
+	})
+})
+
+var _ = Describe("EventLastObserved", func() {
+	It("supports events of both APIs", func() {
+		now := time.Now().Truncate(time.Second)
+		event := &corev1.Event{LastTimestamp: metav1.NewTime(now)}
+		gomega.Expect(EventLastObserved(event)).To(gomega.Equal(now))
+		event = &corev1.Event{EventTime: metav1.NewMicroTime(now)}
+		gomega.Expect(EventLastObserved(event)).To(gomega.Equal(now))
+		event.Series = &corev1.EventSeries{LastObservedTime: metav1.NewMicroTime(now.Add(time.Minute))}
+		gomega.Expect(EventLastObserved(event)).To(gomega.Equal(now.Add(time.Minute)))
+	})
+})
@@ -0,0 +1,168 @@
+/*
+Copyright 2020 Testutil Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/util/exec"
+)
+
+type execOptions struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	TTY    bool
+}
+
+// ExecOption interface is implemented by all possible options to execute a
+// command in a container.
+type ExecOption interface {
+	apply(*execOptions)
+}
+
+type execOptionAdapter func(*execOptions)
+
+func (c execOptionAdapter) apply(o *execOptions) {
+	c(o)
+}
+
+// ExecWithStdin will stream the content of the reader to the stdin of the
+// executed command.
+func ExecWithStdin(stdin io.Reader) ExecOption {
+	return execOptionAdapter(func(o *execOptions) {
+		o.Stdin = stdin
+	})
+}
+
+// execWithStdout will stream stdout to the writer instead of buffering it
+// as part of the result.
+func execWithStdout(stdout io.Writer) ExecOption {
+	return execOptionAdapter(func(o *execOptions) {
+		o.Stdout = stdout
+	})
+}
+
+// ExecWithTTY will allocate a TTY for the command. As a TTY only has a
+// single output stream, stderr will be part of stdout.
+func ExecWithTTY() ExecOption {
+	return execOptionAdapter(func(o *execOptions) {
+		o.TTY = true
+	})
+}
+
+// ExecResult is the outcome of a command executed in a container.
+type ExecResult struct {
+	Stdout   []byte
+	Stderr   []byte
+	ExitCode int
+}
+
+// ExecError is returned by ExecString if the command exited with a non-zero
+// exit code.
+type ExecError struct {
+	*ExecResult
+}
+
+func (e *ExecError) Error() string {
+	return fmt.Sprintf("command terminated with exit code %d: %s",
+		e.ExitCode, strings.TrimSpace(string(e.Stderr)))
+}
+
+// Exec executes the command in the container of the pod. If container is
+// empty, the pod has to have exactly one container. A non-zero exit code
+// is not considered an error, but reported as part of the result.
+//
+// Once the context is done, Exec returns, but as the underlying stream can
+// not be interrupted the command might continue to run.
+func (c *Client) Exec(ctx context.Context, pod *corev1.Pod, container string, cmd []string, opts ...ExecOption) (*ExecResult, error) {
+	options := execOptions{}
+	for _, opt := range opts {
+		opt.apply(&options)
+	}
+	req := c.Clientset.CoreV1().RESTClient().Post().
+		Namespace(pod.Namespace).
+		Resource("pods").
+		Name(pod.Name).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   cmd,
+			Stdin:     options.Stdin != nil,
+			Stdout:    true,
+			Stderr:    !options.TTY,
+			TTY:       options.TTY,
+		}, scheme.ParameterCodec)
+	executor, err := remotecommand.NewSPDYExecutor(c.restConfig, http.MethodPost, req.URL())
+	if err != nil {
+		return nil, err
+	}
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	streamOptions := remotecommand.StreamOptions{
+		Stdin:  options.Stdin,
+		Stdout: stdout,
+		Tty:    options.TTY,
+	}
+	if options.Stdout != nil {
+		streamOptions.Stdout = options.Stdout
+	}
+	if !options.TTY {
+		streamOptions.Stderr = stderr
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- executor.Stream(streamOptions)
+	}()
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case err = <-errCh:
+	}
+	result := &ExecResult{
+		Stdout: stdout.Bytes(),
+		Stderr: stderr.Bytes(),
+	}
+	var exitErr exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.Exited() {
+		result.ExitCode = exitErr.ExitStatus()
+	} else if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ExecString executes the command similar to Exec, but returns stdout as
+// string. If the command exited with a non-zero exit code, an *ExecError is
+// returned.
+func (c *Client) ExecString(ctx context.Context, pod *corev1.Pod, container string, cmd []string, opts ...ExecOption) (string, error) {
+	result, err := c.Exec(ctx, pod, container, cmd, opts...)
+	if err != nil {
+		return "", err
+	}
+	if result.ExitCode != 0 {
+		return string(result.Stdout), &ExecError{result}
+	}
+	return string(result.Stdout), nil
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2020 Testutil Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+var _ = Describe("Exec", func() {
+	It("captures stdout and stderr", func() {
+		pod := mustGetReadyNginxPod(nginxRelease)
+		result, err := k8sClient.Exec(context.Background(), pod, "",
+			[]string{"sh", "-c", "echo out; echo err >&2"})
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		gomega.Expect(string(result.Stdout)).To(gomega.Equal("out\n"))
+		gomega.Expect(string(result.Stderr)).To(gomega.Equal("err\n"))
+		gomega.Expect(result.ExitCode).To(gomega.Equal(0))
+	})
+	It("reports exit code", func() {
+		pod := mustGetReadyNginxPod(nginxRelease)
+		result, err := k8sClient.Exec(context.Background(), pod, "", []string{"sh", "-c", "exit 3"})
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		gomega.Expect(result.ExitCode).To(gomega.Equal(3))
+		_, err = k8sClient.ExecString(context.Background(), pod, "", []string{"sh", "-c", "exit 3"})
+		var execErr *ExecError
+		gomega.Expect(errors.As(err, &execErr)).To(gomega.Equal(true))
+		gomega.Expect(execErr.ExitCode).To(gomega.Equal(3))
+	})
+	It("streams stdin", func() {
+		pod := mustGetReadyNginxPod(nginxRelease)
+		out, err := k8sClient.ExecString(context.Background(), pod, "", []string{"cat"},
+			ExecWithStdin(strings.NewReader("hello")))
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		gomega.Expect(out).To(gomega.Equal("hello"))
+	})
+	It("fails for non-existing pod", func() {
+		pod := PodWithNamespacedName("default", "doesnotexist")
+		_, err := k8sClient.Exec(context.Background(), pod, "", []string{"true"})
+		gomega.Expect(err).To(gomega.HaveOccurred())
+	})
+	It("fails for non-existing container", func() {
+		pod := mustGetReadyNginxPod(nginxRelease)
+		_, err := k8sClient.Exec(context.Background(), pod, "doesnotexist", []string{"true"})
+		gomega.Expect(err).To(gomega.HaveOccurred())
+	})
+})
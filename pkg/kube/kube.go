@@ -17,17 +17,10 @@ limitations under the License.
 package kube
 
 import (
-	"bytes"
 	"context"
 	"fmt"
-	"io"
-	"net/http"
-	"net/url"
-	"strings"
 	"time"
 
-	"github.com/kubism/testutil/pkg/misc"
-
 	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
 	batchv1beta1 "k8s.io/api/batch/v1beta1"
@@ -36,20 +29,21 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/portforward"
-	"k8s.io/client-go/tools/reference"
-	"k8s.io/client-go/transport/spdy"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 )
 
-const PortAny = 0
-
 type clientOptions struct {
-	Scheme *runtime.Scheme
+	Scheme            *runtime.Scheme
+	PollInterval      time.Duration
+	PollBackoffFactor float64
+	PollMaxInterval   time.Duration
+	PollingOnly       bool
+	Tracking          bool
 }
 
 // ClientOption interface is implemented by all possible options to instantiate
@@ -71,23 +65,42 @@ func ClientWithScheme(scheme *runtime.Scheme) ClientOption {
 	})
 }
 
-//
-type Condition interface {
-	check() bool
-	subject() runtime.Object
+// defaultPollInterval is the poll interval used unless configured otherwise.
+const defaultPollInterval = 500 * time.Millisecond
+
+// ClientWithPollInterval sets the initial interval used by WaitUntil, if it
+// has to poll the API server for changes. The same interval is used to
+// re-evaluate conditions while watching. The interval has to be positive.
+func ClientWithPollInterval(interval time.Duration) ClientOption {
+	return clientOptionAdapter(func(o *clientOptions) {
+		o.PollInterval = interval
+	})
 }
 
-type conditionAdapter struct {
-	Check   func() bool
-	Subject runtime.Object
+// ClientWithPollBackoff configures the exponential backoff used while polling.
+// After each unsuccessful check the interval is multiplied by factor until
+// maxInterval is reached. A factor of 1 disables the backoff.
+func ClientWithPollBackoff(factor float64, maxInterval time.Duration) ClientOption {
+	return clientOptionAdapter(func(o *clientOptions) {
+		o.PollBackoffFactor = factor
+		o.PollMaxInterval = maxInterval
+	})
 }
 
-func (c conditionAdapter) check() bool {
-	return c.Check()
+// ClientWithPollingOnly disables watches, so WaitUntil will always poll the
+// API server instead.
+func ClientWithPollingOnly() ClientOption {
+	return clientOptionAdapter(func(o *clientOptions) {
+		o.PollingOnly = true
+	})
 }
 
-func (c conditionAdapter) subject() runtime.Object {
-	return c.Subject
+// ClientWithTracking records every object created through the client, so
+// they can be removed using Cleanup.
+func ClientWithTracking() ClientOption {
+	return clientOptionAdapter(func(o *clientOptions) {
+		o.Tracking = true
+	})
 }
 
 // Client is an extension to the controller-runtime Client and client-go's
@@ -95,20 +108,35 @@ func (c conditionAdapter) subject() runtime.Object {
 // port-forward and more.
 type Client struct {
 	client.Client
-	Clientset  *kubernetes.Clientset
-	restConfig *rest.Config
-	scheme     *runtime.Scheme
+	Clientset     *kubernetes.Clientset
+	restConfig    *rest.Config
+	scheme        *runtime.Scheme
+	mapper        meta.RESTMapper
+	dynamicClient dynamic.Interface
+	tracker       *trackingClient
+	options       clientOptions
 }
 
 func NewClient(restConfig *rest.Config, opts ...ClientOption) (*Client, error) {
 	options := clientOptions{ // Default options
-		Scheme: scheme.Scheme,
+		Scheme:            scheme.Scheme,
+		PollInterval:      defaultPollInterval,
+		PollBackoffFactor: 1.5,
+		PollMaxInterval:   10 * time.Second,
 	}
 	for _, opt := range opts {
 		opt.apply(&options)
 	}
+	if options.PollInterval <= 0 {
+		return nil, fmt.Errorf("poll interval has to be positive, but is %v", options.PollInterval)
+	}
+	mapper, err := apiutil.NewDynamicRESTMapper(restConfig)
+	if err != nil {
+		return nil, err
+	}
 	k8sClient, err := client.New(restConfig, client.Options{
 		Scheme: options.Scheme,
+		Mapper: mapper,
 	})
 	if err != nil {
 		return nil, err
@@ -117,168 +145,60 @@ func NewClient(restConfig *rest.Config, opts ...ClientOption) (*Client, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Client{
-		Client:     k8sClient,
-		Clientset:  clientset,
-		restConfig: restConfig,
-		scheme:     options.Scheme,
-	}, nil
-}
-
-type PortForward struct {
-	LocalPort  int
-	restConfig *rest.Config
-	streams    genericclioptions.IOStreams
-	stopCh     chan struct{}
-	in         *bytes.Buffer
-	out        *bytes.Buffer
-	errout     *bytes.Buffer
-}
-
-func (c *Client) PortForward(pod *corev1.Pod, localPort, podPort int) (*PortForward, error) {
-	var err error
-	if localPort == PortAny {
-		localPort = misc.GetFreePort()
-	}
-	pf := &PortForward{
-		LocalPort:  localPort,
-		restConfig: c.restConfig,
-		stopCh:     make(chan struct{}, 1),
-	}
-	readyCh := make(chan struct{})
-	errorCh := make(chan error, 1)
-	pf.streams, pf.in, pf.out, pf.errout = genericclioptions.NewTestIOStreams()
-	path := fmt.Sprintf("/api/v1/namespaces/%s/pods/%s/portforward", pod.Namespace, pod.Name)
-	hostIP := strings.TrimLeft(pf.restConfig.Host, "htps:/")
-
-	transport, upgrader, err := spdy.RoundTripperFor(pf.restConfig)
-	if err != nil {
-		return nil, err
-	}
-
-	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport},
-		http.MethodPost, &url.URL{Scheme: "https", Path: path, Host: hostIP})
-	fw, err := portforward.New(dialer, []string{fmt.Sprintf("%d:%d", localPort, podPort)},
-		pf.stopCh, readyCh, pf.streams.Out, pf.streams.ErrOut)
+	dynamicClient, err := dynamic.NewForConfig(restConfig)
 	if err != nil {
 		return nil, err
 	}
-	go func() {
-		err := fw.ForwardPorts()
-		errorCh <- err
-	}()
-	select {
-	case <-readyCh:
-		return pf, nil
-	case err := <-errorCh:
-		return nil, err
-	case <-time.After(30 * time.Second):
-		pf.Close()
-		return nil, fmt.Errorf("port-forward did not become ready in time")
+	var tracker *trackingClient
+	if options.Tracking {
+		tracker = &trackingClient{Client: k8sClient}
+		k8sClient = tracker
 	}
+	return &Client{
+		Client:        k8sClient,
+		Clientset:     clientset,
+		restConfig:    restConfig,
+		scheme:        options.Scheme,
+		mapper:        mapper,
+		dynamicClient: dynamicClient,
+		tracker:       tracker,
+		options:       options,
+	}, nil
 }
 
-func (pf *PortForward) Close() error {
-	close(pf.stopCh)
-	return nil
-}
-
-func (c *Client) Logs(ctx context.Context, pod *corev1.Pod) (io.ReadCloser, error) {
-	opts := corev1.PodLogOptions{}
-	req := c.Clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &opts)
-	return req.Stream(ctx)
-}
-
-func (c *Client) LogsString(ctx context.Context, pod *corev1.Pod) (string, error) {
-	readCloser, err := c.Logs(ctx, pod)
-	if err != nil {
-		return "", err
-	}
-	defer readCloser.Close()
-	buf := new(bytes.Buffer)
-	_, err = io.Copy(buf, readCloser)
-	if err != nil {
-		return "", err
-	}
-	return buf.String(), nil
-}
-
-func (c *Client) filterEvents(in []corev1.Event, obj runtime.Object) ([]corev1.Event, error) {
-	out := []corev1.Event{}
-	ref, err := reference.GetReference(c.scheme, obj)
-	if err != nil {
-		return nil, err
-	}
-	ogvk := ref.GroupVersionKind()
-	for _, e := range in {
-		egvk := e.InvolvedObject.GroupVersionKind()
-		if egvk.Kind != ogvk.Kind || egvk.Group != ogvk.Group || egvk.Version != ogvk.Version {
-			continue // well different object, so skip it
-		}
-		if e.InvolvedObject.Name != ref.Name {
-			continue // not the name we are looking for
-		}
-		out = append(out, e) // we found a related event
-	}
-	return out, nil
+// ContainerTermination describes a terminated container of a pod.
+type ContainerTermination struct {
+	Pod       string
+	Container string
+	ExitCode  int32
+	Reason    string
+	Message   string
 }
 
-func (c *Client) Events(ctx context.Context, obj runtime.Object) ([]corev1.Event, error) {
-	accessor, err := meta.Accessor(obj)
-	if err != nil {
-		return nil, err
-	}
-	listOptions := metav1.ListOptions{}
-	list, err := c.Clientset.CoreV1().Events(accessor.GetNamespace()).List(ctx, listOptions)
-	if err != nil {
+// JobTerminations returns the exit codes and termination messages of all
+// terminated containers of pods owned by the job.
+func (c *Client) JobTerminations(ctx context.Context, job *batchv1.Job) ([]ContainerTermination, error) {
+	podList := &corev1.PodList{}
+	if err := c.ListForOwner(ctx, podList, job); err != nil {
 		return nil, err
 	}
-	return c.filterEvents(list.Items, obj)
-}
-
-func (c *Client) ListForOwner(ctx context.Context, list runtime.Object, owner runtime.Object) error {
-	accessor, err := meta.Accessor(owner)
-	if err != nil {
-		return err
-	}
-	if accessor.GetUID() == "" {
-		return fmt.Errorf("owner uid can not be empty")
-	}
-	err = c.List(ctx, list, client.InNamespace(accessor.GetNamespace()))
-	if err != nil {
-		return err
-	}
-	return reduceObjectsByOwner(list, accessor.GetUID())
-}
-
-func reduceObjectsByOwner(list runtime.Object, ownerUID types.UID) error {
-	switch l := list.(type) {
-	case *corev1.PodList:
-		l.Items = reducePodsByOwner(l.Items, ownerUID)
-	case *appsv1.ReplicaSetList:
-		l.Items = reduceReplicaSetsByOwner(l.Items, ownerUID)
-	case *batchv1.JobList:
-		l.Items = reduceJobsByOwner(l.Items, ownerUID)
-	default:
-		return fmt.Errorf("missing implementation for type of %T", list)
-	}
-	return nil
-}
-
-func (c *Client) WaitUntil(ctx context.Context, conditions ...Condition) error {
-	for _, condition := range conditions {
-		objectKey, err := client.ObjectKeyFromObject(condition.subject())
-		if err != nil {
-			return err
-		}
-		for !condition.check() {
-			err := c.Get(ctx, objectKey, condition.subject())
-			if err != nil {
-				return err
+	terminations := []ContainerTermination{}
+	for _, pod := range podList.Items {
+		for _, status := range pod.Status.ContainerStatuses {
+			terminated := status.State.Terminated
+			if terminated == nil {
+				continue
 			}
+			terminations = append(terminations, ContainerTermination{
+				Pod:       pod.Name,
+				Container: status.Name,
+				ExitCode:  terminated.ExitCode,
+				Reason:    terminated.Reason,
+				Message:   terminated.Message,
+			})
 		}
 	}
-	return nil
+	return terminations, nil
 }
 
 func IsPodReady(pod *corev1.Pod) bool {
@@ -293,13 +213,50 @@ func IsPodReady(pod *corev1.Pod) bool {
 	return true
 }
 
-func PodIsReady(pod *corev1.Pod) Condition {
-	return conditionAdapter{
-		Check: func() bool {
-			return IsPodReady(pod)
-		},
-		Subject: pod,
+// terminalWaitingReasons are reasons of waiting containers, which will not
+// resolve without intervention.
+var terminalWaitingReasons = map[string]bool{
+	"ImagePullBackOff":  true,
+	"ErrImageNeverPull": true,
+	"InvalidImageName":  true,
+	"CrashLoopBackOff":  true,
+}
+
+// PodTerminalError returns a *TerminalError if the pod failed or one of its
+// containers is waiting for a reason, which will not resolve by itself, e.g.
+// ImagePullBackOff or CrashLoopBackOff. Otherwise nil is returned.
+func PodTerminalError(pod *corev1.Pod) error {
+	if pod.Status.Phase == corev1.PodFailed {
+		return &TerminalError{
+			Kind:    "Pod",
+			Object:  NamespacedName(pod),
+			Reason:  pod.Status.Reason,
+			Message: pod.Status.Message,
+		}
+	}
+	statuses := append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...)
+	statuses = append(statuses, pod.Status.ContainerStatuses...)
+	for _, status := range statuses {
+		waiting := status.State.Waiting
+		if waiting != nil && terminalWaitingReasons[waiting.Reason] {
+			return &TerminalError{
+				Kind:      "Pod",
+				Object:    NamespacedName(pod),
+				Container: status.Name,
+				Reason:    waiting.Reason,
+				Message:   waiting.Message,
+			}
+		}
 	}
+	return nil
+}
+
+func PodIsReady(pod *corev1.Pod) Condition {
+	return TerminalConditionFunc(pod, "PodIsReady", func() bool {
+		return IsPodReady(pod)
+	}, func() error {
+		return PodTerminalError(pod)
+	})
 }
 
 func getDeploymentReplicas(deployment *appsv1.Deployment) int32 {
@@ -324,31 +281,74 @@ func IsDeploymentUpdated(deployment *appsv1.Deployment) bool {
 	return deployment.Status.UpdatedReplicas == replicas
 }
 
-func DeploymentIsScheduled(deployment *appsv1.Deployment) Condition {
-	return conditionAdapter{
-		Check: func() bool {
-			return IsDeploymentScheduled(deployment)
-		},
-		Subject: deployment,
+// IsDeploymentRolloutComplete returns true if the latest spec of the deployment
+// was observed and rolled out to all replicas, which are available. The
+// semantics are equivalent to `kubectl rollout status`.
+func IsDeploymentRolloutComplete(deployment *appsv1.Deployment) bool {
+	if deployment.Generation > deployment.Status.ObservedGeneration {
+		return false // spec update not observed yet
 	}
+	replicas := getDeploymentReplicas(deployment)
+	if deployment.Status.UpdatedReplicas < replicas {
+		return false // not all replicas updated yet
+	}
+	if deployment.Status.Replicas > deployment.Status.UpdatedReplicas {
+		return false // old replicas are pending termination
+	}
+	if deployment.Status.AvailableReplicas < deployment.Status.UpdatedReplicas {
+		return false // not all updated replicas are available yet
+	}
+	return deployment.Status.UnavailableReplicas == 0
 }
 
-func DeploymentIsReady(deployment *appsv1.Deployment) Condition {
-	return conditionAdapter{
-		Check: func() bool {
-			return IsDeploymentReady(deployment)
-		},
-		Subject: deployment,
+// DeploymentRolloutError returns a *TerminalError if the observed rollout of
+// the deployment exceeded its progress deadline. Otherwise nil is returned.
+func DeploymentRolloutError(deployment *appsv1.Deployment) error {
+	if deployment.Generation > deployment.Status.ObservedGeneration {
+		return nil // progress of the latest spec is unknown
 	}
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentProgressing && condition.Reason == "ProgressDeadlineExceeded" {
+			return &TerminalError{
+				Kind:    "Deployment",
+				Object:  NamespacedName(deployment),
+				Reason:  condition.Reason,
+				Message: condition.Message,
+			}
+		}
+	}
+	return nil
+}
+
+func DeploymentIsScheduled(deployment *appsv1.Deployment) Condition {
+	return ConditionFunc(deployment, "DeploymentIsScheduled", func() bool {
+		return IsDeploymentScheduled(deployment)
+	})
+}
+
+func DeploymentIsReady(deployment *appsv1.Deployment) Condition {
+	return ConditionFunc(deployment, "DeploymentIsReady", func() bool {
+		return IsDeploymentReady(deployment)
+	})
 }
 
 func DeploymentIsUpdated(deployment *appsv1.Deployment) Condition {
-	return conditionAdapter{
-		Check: func() bool {
-			return IsDeploymentUpdated(deployment)
-		},
-		Subject: deployment,
-	}
+	return ConditionFunc(deployment, "DeploymentIsUpdated", func() bool {
+		return IsDeploymentUpdated(deployment)
+	})
+}
+
+// DeploymentRolloutComplete waits until the rollout of the deployment is
+// complete. Contrary to DeploymentIsReady and DeploymentIsUpdated it takes
+// the generation into account, so it can be safely used right after the
+// spec of the deployment was changed. If the progress deadline is exceeded,
+// WaitUntil returns a *TerminalError.
+func DeploymentRolloutComplete(deployment *appsv1.Deployment) Condition {
+	return TerminalConditionFunc(deployment, "DeploymentRolloutComplete", func() bool {
+		return IsDeploymentRolloutComplete(deployment)
+	}, func() error {
+		return DeploymentRolloutError(deployment)
+	})
 }
 
 func getReplicaSetReplicas(rs *appsv1.ReplicaSet) int32 {
@@ -369,89 +369,171 @@ func IsReplicaSetReady(rs *appsv1.ReplicaSet) bool {
 }
 
 func ReplicaSetIsAvailable(rs *appsv1.ReplicaSet) Condition {
-	return conditionAdapter{
-		Check: func() bool {
-			return IsReplicaSetAvailable(rs)
-		},
-		Subject: rs,
-	}
+	return ConditionFunc(rs, "ReplicaSetIsAvailable", func() bool {
+		return IsReplicaSetAvailable(rs)
+	})
 }
 
 func ReplicaSetIsReady(rs *appsv1.ReplicaSet) Condition {
-	return conditionAdapter{
-		Check: func() bool {
-			return IsReplicaSetReady(rs)
-		},
-		Subject: rs,
-	}
-}
-
-func IsJobActive(job *batchv1.Job) bool {
-	return job.Status.Active > 0
+	return ConditionFunc(rs, "ReplicaSetIsReady", func() bool {
+		return IsReplicaSetReady(rs)
+	})
 }
 
-func JobIsActive(job *batchv1.Job) Condition {
-	return conditionAdapter{
-		Check: func() bool {
-			return IsJobActive(job)
-		},
-		Subject: job,
+func getStatefulSetReplicas(sts *appsv1.StatefulSet) int32 {
+	if sts.Spec.Replicas != nil {
+		return *sts.Spec.Replicas
 	}
+	return 1
 }
 
-func IsCronJobActive(cronJob *batchv1beta1.CronJob) bool {
-	if cronJob.Status.Active == nil {
+// IsStatefulSetReady returns true if the latest spec of the statefulset was
+// observed, all replicas are ready and the rolling update is done. For
+// partitioned rolling updates only the replicas above the partition have to
+// be updated, otherwise the current revision has to match the update revision.
+func IsStatefulSetReady(sts *appsv1.StatefulSet) bool {
+	if sts.Status.ObservedGeneration == 0 || sts.Generation > sts.Status.ObservedGeneration {
+		return false // spec update not observed yet
+	}
+	replicas := getStatefulSetReplicas(sts)
+	if sts.Status.ReadyReplicas < replicas {
 		return false
 	}
-	if len(cronJob.Status.Active) > 0 {
-		return true
+	if sts.Spec.UpdateStrategy.Type == appsv1.OnDeleteStatefulSetStrategyType {
+		return true // pods are only updated once deleted, so nothing to wait for
 	}
-	return false
+	rollingUpdate := sts.Spec.UpdateStrategy.RollingUpdate
+	if rollingUpdate != nil && rollingUpdate.Partition != nil && *rollingUpdate.Partition > 0 {
+		return sts.Status.UpdatedReplicas >= replicas-*rollingUpdate.Partition
+	}
+	return sts.Status.CurrentRevision == sts.Status.UpdateRevision
 }
 
-func CronJobIsActive(cronJob *batchv1beta1.CronJob) Condition {
-	return conditionAdapter{
-		Check: func() bool {
-			return IsCronJobActive(cronJob)
-		},
-		Subject: cronJob,
+func StatefulSetIsReady(sts *appsv1.StatefulSet) Condition {
+	return ConditionFunc(sts, "StatefulSetIsReady", func() bool {
+		return IsStatefulSetReady(sts)
+	})
+}
+
+// IsDaemonSetReady returns true if the latest spec of the daemonset was
+// observed and all desired pods are updated, ready and available.
+func IsDaemonSetReady(ds *appsv1.DaemonSet) bool {
+	if ds.Generation > ds.Status.ObservedGeneration {
+		return false // spec update not observed yet
 	}
+	desired := ds.Status.DesiredNumberScheduled
+	return ds.Status.UpdatedNumberScheduled >= desired &&
+		ds.Status.NumberReady >= desired &&
+		ds.Status.NumberAvailable >= desired
+}
+
+func DaemonSetIsReady(ds *appsv1.DaemonSet) Condition {
+	return ConditionFunc(ds, "DaemonSetIsReady", func() bool {
+		return IsDaemonSetReady(ds)
+	})
 }
 
-func reducePodsByOwner(pods []corev1.Pod, ownerUID types.UID) []corev1.Pod {
-	matches := []corev1.Pod{}
-	for _, pod := range pods {
-		for _, ref := range pod.OwnerReferences {
-			if ref.UID == ownerUID {
-				matches = append(matches, pod)
+func IsJobActive(job *batchv1.Job) bool {
+	return job.Status.Active > 0
+}
+
+// JobTerminalError returns a *TerminalError if the job failed, e.g. because
+// it exceeded its backoff limit or deadline. Otherwise nil is returned.
+func JobTerminalError(job *batchv1.Job) error {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			return &TerminalError{
+				Kind:    "Job",
+				Object:  NamespacedName(job),
+				Reason:  condition.Reason,
+				Message: condition.Message,
 			}
 		}
 	}
-	return matches
+	return nil
 }
 
-func reduceReplicaSetsByOwner(replicaSets []appsv1.ReplicaSet, ownerUID types.UID) []appsv1.ReplicaSet {
-	matches := []appsv1.ReplicaSet{}
-	for _, pod := range replicaSets {
-		for _, ref := range pod.OwnerReferences {
-			if ref.UID == ownerUID {
-				matches = append(matches, pod)
-			}
+func JobIsActive(job *batchv1.Job) Condition {
+	return TerminalConditionFunc(job, "JobIsActive", func() bool {
+		return IsJobActive(job)
+	}, func() error {
+		return JobTerminalError(job)
+	})
+}
+
+func hasJobCondition(job *batchv1.Job, conditionType batchv1.JobConditionType) bool {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == conditionType && condition.Status == corev1.ConditionTrue {
+			return true
 		}
 	}
-	return matches
+	return false
+}
+
+// IsJobComplete returns true if the job completed successfully.
+func IsJobComplete(job *batchv1.Job) bool {
+	return hasJobCondition(job, batchv1.JobComplete)
+}
+
+// IsJobFailed returns true if the job failed, e.g. because it exceeded its
+// backoff limit or deadline.
+func IsJobFailed(job *batchv1.Job) bool {
+	return hasJobCondition(job, batchv1.JobFailed)
+}
+
+// IsJobFinished returns true if the job either completed or failed.
+func IsJobFinished(job *batchv1.Job) bool {
+	return IsJobComplete(job) || IsJobFailed(job)
 }
 
-func reduceJobsByOwner(jobs []batchv1.Job, ownerUID types.UID) []batchv1.Job {
-	matches := []batchv1.Job{}
-	for _, pod := range jobs {
-		for _, ref := range pod.OwnerReferences {
-			if ref.UID == ownerUID {
-				matches = append(matches, pod)
+// JobIsComplete waits until the job completed successfully. If the job
+// fails instead, WaitUntil returns a *TerminalError.
+func JobIsComplete(job *batchv1.Job) Condition {
+	return TerminalConditionFunc(job, "JobIsComplete", func() bool {
+		return IsJobComplete(job)
+	}, func() error {
+		return JobTerminalError(job)
+	})
+}
+
+// JobIsFailed waits until the job failed. If the job completes successfully
+// instead, WaitUntil returns a *TerminalError.
+func JobIsFailed(job *batchv1.Job) Condition {
+	return TerminalConditionFunc(job, "JobIsFailed", func() bool {
+		return IsJobFailed(job)
+	}, func() error {
+		if IsJobComplete(job) {
+			return &TerminalError{
+				Kind:   "Job",
+				Object: NamespacedName(job),
+				Reason: string(batchv1.JobComplete),
 			}
 		}
+		return nil
+	})
+}
+
+// JobIsFinished waits until the job either completed or failed.
+func JobIsFinished(job *batchv1.Job) Condition {
+	return ConditionFunc(job, "JobIsFinished", func() bool {
+		return IsJobFinished(job)
+	})
+}
+
+func IsCronJobActive(cronJob *batchv1beta1.CronJob) bool {
+	if cronJob.Status.Active == nil {
+		return false
+	}
+	if len(cronJob.Status.Active) > 0 {
+		return true
 	}
-	return matches
+	return false
+}
+
+func CronJobIsActive(cronJob *batchv1beta1.CronJob) Condition {
+	return ConditionFunc(cronJob, "CronJobIsActive", func() bool {
+		return IsCronJobActive(cronJob)
+	})
 }
 
 // NamespaceName conveniently creates a NamespacedName from any valid kubernetes
@@ -494,6 +576,24 @@ func DeploymentWithNamespacedName(namespace, name string) *appsv1.Deployment {
 	}
 }
 
+func StatefulSetWithNamespacedName(namespace, name string) *appsv1.StatefulSet {
+	return &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+		},
+	}
+}
+
+func DaemonSetWithNamespacedName(namespace, name string) *appsv1.DaemonSet {
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+		},
+	}
+}
+
 func JobWithNamespacedName(namespace, name string) *batchv1.Job {
 	return &batchv1.Job{
 		ObjectMeta: metav1.ObjectMeta{
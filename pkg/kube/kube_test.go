@@ -18,116 +18,53 @@ package kube
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"time"
 
+	"github.com/kubism/testutil/pkg/rand"
+
 	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
 
 	. "github.com/onsi/ginkgo"
-	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega"
 )
 
 var _ = Describe("Client", func() {
 	It("is successful with valid configuration", func() {
 		c, err := NewClient(restConfig, ClientWithScheme(scheme.Scheme))
-		Expect(err).To(Succeed())
-		Expect(c).ToNot(BeNil())
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(c).ToNot(gomega.BeNil())
 	})
 	It("fails with invalid REST config", func() {
 		Context("empty host", func() {
 			brokenRESTConfig, err := cluster.GetRESTConfig()
-			Expect(err).ToNot(HaveOccurred())
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
 			brokenRESTConfig.Host = ""
 			c, err := NewClient(brokenRESTConfig)
-			Expect(err).To(HaveOccurred())
-			Expect(c).To(BeNil())
+			gomega.Expect(err).To(gomega.HaveOccurred())
+			gomega.Expect(c).To(gomega.BeNil())
 		})
 		Context("missing CA", func() {
 			brokenRESTConfig, err := cluster.GetRESTConfig()
-			Expect(err).ToNot(HaveOccurred())
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
 			brokenRESTConfig.CAFile = ""
 			brokenRESTConfig.CAData = []byte{}
 			c, err := NewClient(brokenRESTConfig)
-			Expect(err).To(HaveOccurred())
-			Expect(c).To(BeNil())
+			gomega.Expect(err).To(gomega.HaveOccurred())
+			gomega.Expect(c).To(gomega.BeNil())
 		})
 	})
-})
-
-var _ = Describe("PortForward", func() {
-	It("can portforward existing pod", func() {
-		rls := mustInstallNginx()
-		defer helmClient.Uninstall(rls.Name) // nolint:errcheck
-		pod := mustGetReadyNginxPod(rls)
-		Expect(func() {
-			Expect(k8sClient.Get(context.Background(), NamespacedName(pod), pod)).To(Succeed())
-		}).ShouldNot(Panic())
-		By("creating port-forward")
-		pf, err := k8sClient.PortForward(pod, PortAny, 8080)
-		Expect(err).ToNot(HaveOccurred())
-		defer pf.Close()
-		Expect(checkNginxServer(fmt.Sprintf("http://localhost:%d", pf.LocalPort))).To(Succeed())
-	})
-	It("fails with invalid host port", func() {
-		rls := mustInstallNginx()
-		defer helmClient.Uninstall(rls.Name) // nolint:errcheck
-		pod := mustGetReadyNginxPod(rls)
-		pf, err := k8sClient.PortForward(pod, 999999, 8080)
-		Expect(err).To(HaveOccurred())
-		Expect(pf).To(BeNil())
-	})
-	It("fails for non-existing pod", func() {
-		var pod corev1.Pod
-		pod.ObjectMeta.Namespace = "default"
-		pod.ObjectMeta.Name = "doesnotexist"
-		pf, err := k8sClient.PortForward(&pod, PortAny, 8080)
-		Expect(err).To(HaveOccurred())
-		Expect(pf).To(BeNil())
-	})
-})
-
-var _ = Describe("Logs", func() {
-	It("can get logs of existing pod", func() {
-		pod := mustGetReadyNginxPod(nginxRelease)
-		logs, err := k8sClient.LogsString(context.Background(), pod)
-		Expect(err).ToNot(HaveOccurred())
-		Expect(len(logs)).To(BeNumerically(">", 0))
-	})
-	It("fails if pod does not exist", func() {
-		var pod corev1.Pod
-		pod.Namespace = "default"
-		pod.Name = "doesnotexist"
-		_, err := k8sClient.LogsString(context.Background(), &pod)
-		Expect(err).To(HaveOccurred())
-	})
-})
-
-var _ = Describe("Events", func() {
-	It("can get events for existing pod", func() {
-		pod := mustGetReadyNginxPod(nginxRelease)
-		events, err := k8sClient.Events(context.Background(), pod)
-		Expect(err).ToNot(HaveOccurred())
-		Expect(len(events)).To(BeNumerically(">", 0))
-	})
-})
-
-var _ = Describe("ListForOwner", func() {
-	// for successful usage, see `WaitUntilJobActive`-tests
-	It("fails for non-existing object", func() {
-		job := genPiJob()
-		job.Namespace = "doesnotexist"
-		podList := &corev1.PodList{}
-		Expect(k8sClient.ListForOwner(context.Background(), podList, job)).ToNot(Succeed())
-	})
-	It("fails for malformed object", func() {
-		job := genPiJob()
-		job.Namespace = "+"
-		podList := &corev1.PodList{}
-		Expect(k8sClient.ListForOwner(context.Background(), podList, job)).ToNot(Succeed())
+	It("fails with non-positive poll interval", func() {
+		for _, interval := range []time.Duration{0, -time.Second} {
+			c, err := NewClient(restConfig, ClientWithPollInterval(interval))
+			gomega.Expect(err).To(gomega.HaveOccurred())
+			gomega.Expect(c).To(gomega.BeNil())
+		}
 	})
 })
 
@@ -135,23 +72,27 @@ type testCondition struct {
 	Object runtime.Object
 }
 
-func (c testCondition) subject() runtime.Object {
+func (c testCondition) Subject() runtime.Object {
 	return c.Object
 }
 
-func (_ testCondition) check() bool {
+func (_ testCondition) Check() bool {
 	return false
 }
 
+func (_ testCondition) Description() string {
+	return "never met"
+}
+
 var _ = Describe("WaitUntil", func() {
 	It("fails for condition with nil subject", func() {
-		Expect(k8sClient.WaitUntil(context.Background(), testCondition{})).ToNot(Succeed())
+		gomega.Expect(k8sClient.WaitUntil(context.Background(), testCondition{})).ToNot(gomega.Succeed())
 	})
 	It("can timeout if check does not become true", func() {
 		deployment := DeploymentWithNamespacedName(nginxRelease.Namespace, nginxRelease.Name+"-nginx")
-		Expect(k8sClient.Get(context.Background(), NamespacedName(deployment), deployment)).To(Succeed())
+		gomega.Expect(k8sClient.Get(context.Background(), NamespacedName(deployment), deployment)).To(gomega.Succeed())
 		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
-		Expect(k8sClient.WaitUntil(ctx, testCondition{deployment})).ToNot(Succeed())
+		gomega.Expect(k8sClient.WaitUntil(ctx, testCondition{deployment})).ToNot(gomega.Succeed())
 		defer cancel()
 	})
 	It("waits until deployment ready", func() {
@@ -160,14 +101,58 @@ var _ = Describe("WaitUntil", func() {
 		ctx, cancel := context.WithTimeout(context.Background(), timeout)
 		defer cancel()
 		deployment := DeploymentWithNamespacedName(rls.Namespace, rls.Name+"-nginx")
-		Expect(k8sClient.Get(ctx, NamespacedName(deployment), deployment)).To(Succeed())
-		Expect(k8sClient.WaitUntil(ctx, DeploymentIsReady(deployment))).To(Succeed())
-		Expect(IsDeploymentReady(deployment)).To(Equal(true))
+		gomega.Expect(k8sClient.Get(ctx, NamespacedName(deployment), deployment)).To(gomega.Succeed())
+		gomega.Expect(k8sClient.WaitUntil(ctx, DeploymentIsReady(deployment))).To(gomega.Succeed())
+		gomega.Expect(IsDeploymentReady(deployment)).To(gomega.Equal(true))
+	})
+	It("waits until deployment ready by polling", func() {
+		pollingClient, err := NewClient(restConfig, ClientWithPollingOnly(),
+			ClientWithPollInterval(100*time.Millisecond), ClientWithPollBackoff(2, time.Second))
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		rls := mustInstallNginx()
+		defer helmClient.Uninstall(rls.Name) // nolint:errcheck
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		deployment := DeploymentWithNamespacedName(rls.Namespace, rls.Name+"-nginx")
+		gomega.Expect(pollingClient.WaitUntil(ctx, DeploymentIsReady(deployment))).To(gomega.Succeed())
+		gomega.Expect(IsDeploymentReady(deployment)).To(gomega.Equal(true))
+	})
+	It("returns context error once deadline is exceeded", func() {
+		deployment := DeploymentWithNamespacedName(nginxRelease.Namespace, nginxRelease.Name+"-nginx")
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		defer cancel()
+		err := k8sClient.WaitUntil(ctx, testCondition{deployment})
+		gomega.Expect(errors.Is(err, context.DeadlineExceeded)).To(gomega.Equal(true))
+	})
+	It("provides diagnostics if condition is not met in time", func() {
+		pod := mustGetReadyNginxPod(nginxRelease)
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		defer cancel()
+		err := k8sClient.WaitUntil(ctx, testCondition{pod})
+		var waitErr *WaitError
+		gomega.Expect(errors.As(err, &waitErr)).To(gomega.Equal(true))
+		gomega.Expect(waitErr.Condition).To(gomega.Equal("never met"))
+		gomega.Expect(err.Error()).To(gomega.HavePrefix("condition never met not met for "))
+		gomega.Expect(waitErr.Subject).ToNot(gomega.BeNil())
+		gomega.Expect(waitErr.Status).To(gomega.HaveKey("phase"))
+		gomega.Expect(len(waitErr.Events)).To(gomega.BeNumerically(">", 0))
+		gomega.Expect(err.Error()).To(gomega.ContainSubstring("recent events"))
+	})
+	It("waits until custom condition is met", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		deployment := DeploymentWithNamespacedName(nginxRelease.Namespace, nginxRelease.Name+"-nginx")
+		condition := ConditionFunc(deployment, "deployment has available replicas", func() bool {
+			return deployment.Status.AvailableReplicas > 0
+		})
+		gomega.Expect(condition.Description()).To(gomega.Equal("deployment has available replicas"))
+		gomega.Expect(k8sClient.WaitUntil(ctx, condition)).To(gomega.Succeed())
+		gomega.Expect(deployment.Status.AvailableReplicas).To(gomega.BeNumerically(">", 0))
 	})
 	It("fails for non-existing deployment", func() {
 		ctx, cancel := context.WithTimeout(context.Background(), timeout)
 		defer cancel()
-		Expect(k8sClient.WaitUntil(ctx, DeploymentIsReady(&appsv1.Deployment{}))).NotTo(Succeed())
+		gomega.Expect(k8sClient.WaitUntil(ctx, DeploymentIsReady(&appsv1.Deployment{}))).NotTo(gomega.Succeed())
 	})
 	It("waits until deployment scheduled", func() {
 		rls := mustInstallNginx()
@@ -175,9 +160,9 @@ var _ = Describe("WaitUntil", func() {
 		ctx, cancel := context.WithTimeout(context.Background(), timeout)
 		defer cancel()
 		deployment := DeploymentWithNamespacedName(rls.Namespace, rls.Name+"-nginx")
-		Expect(k8sClient.Get(ctx, NamespacedName(deployment), deployment)).To(Succeed())
-		Expect(k8sClient.WaitUntil(ctx, DeploymentIsScheduled(deployment))).To(Succeed())
-		Expect(IsDeploymentScheduled(deployment)).To(Equal(true))
+		gomega.Expect(k8sClient.Get(ctx, NamespacedName(deployment), deployment)).To(gomega.Succeed())
+		gomega.Expect(k8sClient.WaitUntil(ctx, DeploymentIsScheduled(deployment))).To(gomega.Succeed())
+		gomega.Expect(IsDeploymentScheduled(deployment)).To(gomega.Equal(true))
 	})
 	It("waits until deployment updated", func() {
 		rls := mustInstallNginx()
@@ -185,22 +170,95 @@ var _ = Describe("WaitUntil", func() {
 		ctx, cancel := context.WithTimeout(context.Background(), timeout)
 		defer cancel()
 		deployment := DeploymentWithNamespacedName(rls.Namespace, rls.Name+"-nginx")
-		Expect(k8sClient.Get(ctx, NamespacedName(deployment), deployment)).To(Succeed())
-		Expect(k8sClient.WaitUntil(ctx, DeploymentIsUpdated(deployment))).To(Succeed())
-		Expect(IsDeploymentUpdated(deployment)).To(Equal(true))
+		gomega.Expect(k8sClient.Get(ctx, NamespacedName(deployment), deployment)).To(gomega.Succeed())
+		gomega.Expect(k8sClient.WaitUntil(ctx, DeploymentIsUpdated(deployment))).To(gomega.Succeed())
+		gomega.Expect(IsDeploymentUpdated(deployment)).To(gomega.Equal(true))
+	})
+	It("waits until deployment rollout is complete", func() {
+		rls := mustInstallNginx()
+		defer helmClient.Uninstall(rls.Name) // nolint:errcheck
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		deployment := DeploymentWithNamespacedName(rls.Namespace, rls.Name+"-nginx")
+		gomega.Expect(k8sClient.WaitUntil(ctx, DeploymentRolloutComplete(deployment))).To(gomega.Succeed())
+		By("changing the pod template")
+		deployment.Spec.Template.Annotations = map[string]string{"testutil": rand.String(5)}
+		gomega.Expect(k8sClient.Update(ctx, deployment)).To(gomega.Succeed())
+		gomega.Expect(IsDeploymentRolloutComplete(deployment)).To(gomega.Equal(false))
+		gomega.Expect(k8sClient.WaitUntil(ctx, DeploymentRolloutComplete(deployment))).To(gomega.Succeed())
+		gomega.Expect(deployment.Status.ObservedGeneration).To(gomega.Equal(deployment.Generation))
+	})
+	It("detects exceeded progress deadline", func() {
+		deployment := DeploymentWithNamespacedName("default", "a")
+		deployment.Status.Conditions = []appsv1.DeploymentCondition{{
+			Type:   appsv1.DeploymentProgressing,
+			Status: corev1.ConditionFalse,
+			Reason: "ProgressDeadlineExceeded",
+		}}
+		gomega.Expect(DeploymentRolloutError(deployment)).ToNot(gomega.Succeed())
+		deployment.Generation = 1
+		gomega.Expect(DeploymentRolloutError(deployment)).To(gomega.Succeed())
 	})
 	It("waits until replicaset available and ready", func() {
 		ctx, cancel := context.WithTimeout(context.Background(), timeout)
 		defer cancel()
 		deployment := DeploymentWithNamespacedName(nginxRelease.Namespace, nginxRelease.Name+"-nginx")
-		Expect(k8sClient.Get(ctx, NamespacedName(deployment), deployment)).To(Succeed())
+		gomega.Expect(k8sClient.Get(ctx, NamespacedName(deployment), deployment)).To(gomega.Succeed())
 		replicaSetList := &appsv1.ReplicaSetList{}
-		Expect(k8sClient.ListForOwner(context.Background(), replicaSetList, deployment)).To(Succeed())
-		Expect(len(replicaSetList.Items)).To(BeNumerically(">", 0))
+		gomega.Expect(k8sClient.ListForOwner(context.Background(), replicaSetList, deployment)).To(gomega.Succeed())
+		gomega.Expect(len(replicaSetList.Items)).To(gomega.BeNumerically(">", 0))
 		rs := &replicaSetList.Items[0]
-		Expect(k8sClient.WaitUntil(ctx, ReplicaSetIsAvailable(rs), ReplicaSetIsReady(rs))).To(Succeed())
-		Expect(IsReplicaSetAvailable(rs)).To(Equal(true))
-		Expect(IsReplicaSetReady(rs)).To(Equal(true))
+		gomega.Expect(k8sClient.WaitUntil(ctx, ReplicaSetIsAvailable(rs), ReplicaSetIsReady(rs))).To(gomega.Succeed())
+		gomega.Expect(IsReplicaSetAvailable(rs)).To(gomega.Equal(true))
+		gomega.Expect(IsReplicaSetReady(rs)).To(gomega.Equal(true))
+	})
+	It("waits until statefulset is ready", func() {
+		sts := mustCreateNginxStatefulSet()
+		defer func() {
+			_ = k8sClient.Delete(context.Background(), sts)
+		}()
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		gomega.Expect(k8sClient.WaitUntil(ctx, StatefulSetIsReady(sts))).To(gomega.Succeed())
+		gomega.Expect(IsStatefulSetReady(sts)).To(gomega.Equal(true))
+		podList := &corev1.PodList{}
+		gomega.Expect(k8sClient.ListForOwner(ctx, podList, sts)).To(gomega.Succeed())
+		gomega.Expect(len(podList.Items)).To(gomega.Equal(1))
+	})
+	It("considers partitioned statefulset updates", func() {
+		replicas, partition := int32(3), int32(2)
+		sts := StatefulSetWithNamespacedName("default", "a")
+		sts.Generation = 2
+		sts.Spec.Replicas = &replicas
+		sts.Spec.UpdateStrategy = appsv1.StatefulSetUpdateStrategy{
+			Type: appsv1.RollingUpdateStatefulSetStrategyType,
+			RollingUpdate: &appsv1.RollingUpdateStatefulSetStrategy{
+				Partition: &partition,
+			},
+		}
+		sts.Status = appsv1.StatefulSetStatus{
+			ObservedGeneration: 2,
+			ReadyReplicas:      3,
+			UpdatedReplicas:    1,
+			CurrentRevision:    "a",
+			UpdateRevision:     "b",
+		}
+		gomega.Expect(IsStatefulSetReady(sts)).To(gomega.Equal(true))
+		sts.Status.UpdatedReplicas = 0
+		gomega.Expect(IsStatefulSetReady(sts)).To(gomega.Equal(false))
+	})
+	It("waits until daemonset is ready", func() {
+		ds := mustCreateNginxDaemonSet()
+		defer func() {
+			_ = k8sClient.Delete(context.Background(), ds)
+		}()
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		gomega.Expect(k8sClient.WaitUntil(ctx, DaemonSetIsReady(ds))).To(gomega.Succeed())
+		gomega.Expect(IsDaemonSetReady(ds)).To(gomega.Equal(true))
+		podList := &corev1.PodList{}
+		gomega.Expect(k8sClient.ListForOwner(ctx, podList, ds)).To(gomega.Succeed())
+		gomega.Expect(len(podList.Items)).To(gomega.BeNumerically(">", 0))
 	})
 	It("waits until job is active", func() {
 		job := mustCreatePiJob()
@@ -209,11 +267,47 @@ var _ = Describe("WaitUntil", func() {
 		}()
 		ctx, cancel := context.WithTimeout(context.Background(), timeout)
 		defer cancel()
-		Expect(k8sClient.WaitUntil(ctx, JobIsActive(job))).To(Succeed())
-		Expect(IsJobActive(job)).To(Equal(true))
+		gomega.Expect(k8sClient.WaitUntil(ctx, JobIsActive(job))).To(gomega.Succeed())
+		gomega.Expect(IsJobActive(job)).To(gomega.Equal(true))
 		podList := &corev1.PodList{}
-		Expect(k8sClient.ListForOwner(context.Background(), podList, job)).To(Succeed())
-		Expect(len(podList.Items)).To(Equal(1))
+		gomega.Expect(k8sClient.ListForOwner(context.Background(), podList, job)).To(gomega.Succeed())
+		gomega.Expect(len(podList.Items)).To(gomega.Equal(1))
+	})
+	It("waits until job is complete", func() {
+		job := mustCreatePiJob()
+		defer func() {
+			_ = k8sClient.Delete(context.Background(), job)
+		}()
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		gomega.Expect(k8sClient.WaitUntil(ctx, JobIsFinished(job), JobIsComplete(job))).To(gomega.Succeed())
+		gomega.Expect(IsJobComplete(job)).To(gomega.Equal(true))
+		gomega.Expect(IsJobFailed(job)).To(gomega.Equal(false))
+		terminations, err := k8sClient.JobTerminations(ctx, job)
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		gomega.Expect(len(terminations)).To(gomega.Equal(1))
+		gomega.Expect(terminations[0].ExitCode).To(gomega.Equal(int32(0)))
+	})
+	It("waits until job is failed", func() {
+		job := genPiJob()
+		backoffLimit := int32(0)
+		job.Spec.BackoffLimit = &backoffLimit
+		job.Spec.Template.Spec.Containers[0].Command = []string{"sh", "-c", "echo failed > /dev/termination-log; exit 3"}
+		gomega.Expect(k8sClient.Create(context.Background(), job)).To(gomega.Succeed())
+		defer func() {
+			_ = k8sClient.Delete(context.Background(), job)
+		}()
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		err := k8sClient.WaitUntil(ctx, JobIsComplete(job))
+		var terminalErr *TerminalError
+		gomega.Expect(errors.As(err, &terminalErr)).To(gomega.Equal(true))
+		gomega.Expect(k8sClient.WaitUntil(ctx, JobIsFailed(job))).To(gomega.Succeed())
+		terminations, err := k8sClient.JobTerminations(ctx, job)
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		gomega.Expect(len(terminations)).To(gomega.Equal(1))
+		gomega.Expect(terminations[0].ExitCode).To(gomega.Equal(int32(3)))
+		gomega.Expect(terminations[0].Message).To(gomega.ContainSubstring("failed"))
 	})
 	It("waits until cronjob is active", func() {
 		cronJob := mustCreatePiCronJob()
@@ -222,58 +316,131 @@ var _ = Describe("WaitUntil", func() {
 		}()
 		ctx, cancel := context.WithTimeout(context.Background(), timeout)
 		defer cancel()
-		Expect(k8sClient.WaitUntil(ctx, CronJobIsActive(cronJob))).To(Succeed())
-		Expect(IsCronJobActive(cronJob)).To(Equal(true))
+		gomega.Expect(k8sClient.WaitUntil(ctx, CronJobIsActive(cronJob))).To(gomega.Succeed())
+		gomega.Expect(IsCronJobActive(cronJob)).To(gomega.Equal(true))
 		jobList := &batchv1.JobList{}
-		Expect(k8sClient.ListForOwner(ctx, jobList, cronJob)).To(Succeed())
-		Expect(len(jobList.Items)).To(Equal(1))
+		gomega.Expect(k8sClient.ListForOwner(ctx, jobList, cronJob)).To(gomega.Succeed())
+		gomega.Expect(len(jobList.Items)).To(gomega.Equal(1))
+	})
+})
+
+var _ = Describe("TerminalError", func() {
+	It("detects containers waiting in terminal state", func() {
+		pod := PodWithNamespacedName("default", "a")
+		gomega.Expect(PodTerminalError(pod)).To(gomega.Succeed())
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+			Name: "nginx",
+			State: corev1.ContainerState{
+				Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"},
+			},
+		}}
+		err := PodTerminalError(pod)
+		var terminalErr *TerminalError
+		gomega.Expect(errors.As(err, &terminalErr)).To(gomega.Equal(true))
+		gomega.Expect(terminalErr.Container).To(gomega.Equal("nginx"))
+		gomega.Expect(terminalErr.Reason).To(gomega.Equal("CrashLoopBackOff"))
+	})
+	It("detects failed jobs", func() {
+		job := JobWithNamespacedName("default", "a")
+		gomega.Expect(JobTerminalError(job)).To(gomega.Succeed())
+		job.Status.Conditions = []batchv1.JobCondition{{
+			Type:   batchv1.JobFailed,
+			Status: corev1.ConditionTrue,
+			Reason: "BackoffLimitExceeded",
+		}}
+		gomega.Expect(JobTerminalError(job)).ToNot(gomega.Succeed())
+	})
+	It("fails fast for pod which can not pull its image", func() {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "default",
+				Name:      "invalid-image-" + rand.String(5),
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{
+					Name:  "invalid",
+					Image: "doesnotexist.invalid/image:latest",
+				}},
+			},
+		}
+		gomega.Expect(k8sClient.Create(context.Background(), pod)).To(gomega.Succeed())
+		defer func() {
+			_ = k8sClient.Delete(context.Background(), pod)
+		}()
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		err := k8sClient.WaitUntil(ctx, PodIsReady(pod))
+		var terminalErr *TerminalError
+		gomega.Expect(errors.As(err, &terminalErr)).To(gomega.Equal(true))
+		gomega.Expect(terminalErr.Container).To(gomega.Equal("invalid"))
+		gomega.Expect(ctx.Err()).To(gomega.BeNil())
 	})
 })
 
 var _ = Describe("NamespacedName", func() {
 	It("can retrieve namespace and name", func() {
-		Expect(func() {
+		gomega.Expect(func() {
 			pod := PodWithNamespacedName("a", "b")
 			namespacedName := NamespacedName(pod)
-			Expect(namespacedName.Namespace).To(Equal(pod.Namespace))
-			Expect(namespacedName.Name).To(Equal(pod.Name))
-		}).ShouldNot(Panic())
+			gomega.Expect(namespacedName.Namespace).To(gomega.Equal(pod.Namespace))
+			gomega.Expect(namespacedName.Name).To(gomega.Equal(pod.Name))
+		}).ShouldNot(gomega.Panic())
 	})
 	It("fails for invalid types", func() {
-		Expect(func() {
+		gomega.Expect(func() {
 			_ = NamespacedName(nil)
-		}).Should(Panic())
+		}).Should(gomega.Panic())
 	})
 	It("works for existing replicaset", func() {
-		Expect(func() {
+		gomega.Expect(func() {
 			deployment := DeploymentWithNamespacedName(nginxRelease.Namespace, nginxRelease.Name+"-nginx")
-			Expect(k8sClient.Get(context.Background(), NamespacedName(deployment), deployment)).To(Succeed())
+			gomega.Expect(k8sClient.Get(context.Background(), NamespacedName(deployment), deployment)).To(gomega.Succeed())
 			replicaSetList := &appsv1.ReplicaSetList{}
-			Expect(k8sClient.ListForOwner(context.Background(), replicaSetList, deployment)).To(Succeed())
-			Expect(len(replicaSetList.Items)).To(BeNumerically(">", 0))
+			gomega.Expect(k8sClient.ListForOwner(context.Background(), replicaSetList, deployment)).To(gomega.Succeed())
+			gomega.Expect(len(replicaSetList.Items)).To(gomega.BeNumerically(">", 0))
 			rs := &replicaSetList.Items[0]
 			tmp := ReplicaSetWithNamespacedName(rs.Namespace, rs.Name)
-			Expect(k8sClient.Get(context.Background(), NamespacedName(tmp), tmp)).To(Succeed())
-		}).ShouldNot(Panic())
+			gomega.Expect(k8sClient.Get(context.Background(), NamespacedName(tmp), tmp)).To(gomega.Succeed())
+		}).ShouldNot(gomega.Panic())
+	})
+	It("works for existing statefulset", func() {
+		gomega.Expect(func() {
+			sts := mustCreateNginxStatefulSet()
+			defer func() {
+				_ = k8sClient.Delete(context.Background(), sts)
+			}()
+			tmp := StatefulSetWithNamespacedName(sts.Namespace, sts.Name)
+			gomega.Expect(k8sClient.Get(context.Background(), NamespacedName(tmp), tmp)).To(gomega.Succeed())
+		}).ShouldNot(gomega.Panic())
+	})
+	It("works for existing daemonset", func() {
+		gomega.Expect(func() {
+			ds := mustCreateNginxDaemonSet()
+			defer func() {
+				_ = k8sClient.Delete(context.Background(), ds)
+			}()
+			tmp := DaemonSetWithNamespacedName(ds.Namespace, ds.Name)
+			gomega.Expect(k8sClient.Get(context.Background(), NamespacedName(tmp), tmp)).To(gomega.Succeed())
+		}).ShouldNot(gomega.Panic())
 	})
 	It("works for existing job", func() {
-		Expect(func() {
+		gomega.Expect(func() {
 			job := mustCreatePiJob()
 			defer func() {
 				_ = k8sClient.Delete(context.Background(), job)
 			}()
 			tmp := JobWithNamespacedName(job.Namespace, job.Name)
-			Expect(k8sClient.Get(context.Background(), NamespacedName(tmp), tmp)).To(Succeed())
-		}).ShouldNot(Panic())
+			gomega.Expect(k8sClient.Get(context.Background(), NamespacedName(tmp), tmp)).To(gomega.Succeed())
+		}).ShouldNot(gomega.Panic())
 	})
 	It("works for existing cronjob", func() {
-		Expect(func() {
+		gomega.Expect(func() {
 			cronJob := mustCreatePiCronJob()
 			defer func() {
 				_ = k8sClient.Delete(context.Background(), cronJob)
 			}()
 			tmp := CronJobWithNamespacedName(cronJob.Namespace, cronJob.Name)
-			Expect(k8sClient.Get(context.Background(), NamespacedName(tmp), tmp)).To(Succeed())
-		}).ShouldNot(Panic())
+			gomega.Expect(k8sClient.Get(context.Background(), NamespacedName(tmp), tmp)).To(gomega.Succeed())
+		}).ShouldNot(gomega.Panic())
 	})
 })
@@ -0,0 +1,416 @@
+/*
+Copyright 2020 Testutil Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// LogsOption interface is implemented by all possible options to retrieve
+// logs of a pod.
+type LogsOption interface {
+	apply(*corev1.PodLogOptions)
+}
+
+type logsOptionAdapter func(*corev1.PodLogOptions)
+
+func (c logsOptionAdapter) apply(o *corev1.PodLogOptions) {
+	c(o)
+}
+
+// LogsWithContainer will retrieve the logs of the container, which is
+// required for pods with multiple containers.
+func LogsWithContainer(container string) LogsOption {
+	return logsOptionAdapter(func(o *corev1.PodLogOptions) {
+		o.Container = container
+	})
+}
+
+// LogsWithPrevious will retrieve the logs of the previous terminated instance
+// of the container, e.g. after it crashed.
+func LogsWithPrevious() LogsOption {
+	return logsOptionAdapter(func(o *corev1.PodLogOptions) {
+		o.Previous = true
+	})
+}
+
+// LogsWithFollow will keep the stream open and follow the logs until the
+// container terminates or the context is done.
+func LogsWithFollow() LogsOption {
+	return logsOptionAdapter(func(o *corev1.PodLogOptions) {
+		o.Follow = true
+	})
+}
+
+// LogsWithSinceTime will only retrieve logs written at or after the time.
+func LogsWithSinceTime(since time.Time) LogsOption {
+	return logsOptionAdapter(func(o *corev1.PodLogOptions) {
+		sinceTime := metav1.NewTime(since)
+		o.SinceTime = &sinceTime
+	})
+}
+
+// LogsWithTailLines will only retrieve the last lines of the logs.
+func LogsWithTailLines(lines int64) LogsOption {
+	return logsOptionAdapter(func(o *corev1.PodLogOptions) {
+		o.TailLines = &lines
+	})
+}
+
+// LogsWithTimestamps will prefix every line with a RFC3339 timestamp.
+func LogsWithTimestamps() LogsOption {
+	return logsOptionAdapter(func(o *corev1.PodLogOptions) {
+		o.Timestamps = true
+	})
+}
+
+// Logs streams the logs of the pod. Make sure to always close the returned
+// stream.
+func (c *Client) Logs(ctx context.Context, pod *corev1.Pod, opts ...LogsOption) (io.ReadCloser, error) {
+	options := corev1.PodLogOptions{}
+	for _, opt := range opts {
+		opt.apply(&options)
+	}
+	req := c.Clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &options)
+	return req.Stream(ctx)
+}
+
+// LogsString retrieves the logs of the pod as string.
+func (c *Client) LogsString(ctx context.Context, pod *corev1.Pod, opts ...LogsOption) (string, error) {
+	readCloser, err := c.Logs(ctx, pod, opts...)
+	if err != nil {
+		return "", err
+	}
+	defer readCloser.Close()
+	buf := new(bytes.Buffer)
+	_, err = io.Copy(buf, readCloser)
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// LogsForSelector follows the logs of all containers of all pods in the
+// namespace matching the selector and writes them line by line to the writer
+// prefixed by `pod/container`. Pods appearing later and restarted containers
+// are picked up as well. It blocks until the context is done, so it is
+// usually run in its own goroutine. LogsWithContainer limits the streamed
+// containers, while LogsWithFollow is always set.
+func (c *Client) LogsForSelector(ctx context.Context, namespace string, selector labels.Selector, w io.Writer, opts ...LogsOption) error {
+	options := corev1.PodLogOptions{}
+	for _, opt := range opts {
+		opt.apply(&options)
+	}
+	options.Follow = true
+	options.Previous = false
+	out := &prefixWriter{w: w}
+	mu := sync.Mutex{}                    // guards streamed and interrupted
+	streamed := map[string]int32{}        // restart count of streamed containers
+	interrupted := map[string]time.Time{} // last line of interrupted streams
+	wg := sync.WaitGroup{}
+	defer wg.Wait()
+	for {
+		podList := &corev1.PodList{}
+		err := c.List(ctx, podList, client.InNamespace(namespace),
+			client.MatchingLabelsSelector{Selector: selector})
+		if err != nil && ctx.Err() == nil {
+			return err
+		}
+		for i := range podList.Items {
+			pod := &podList.Items[i]
+			for _, status := range pod.Status.ContainerStatuses {
+				if options.Container != "" && status.Name != options.Container {
+					continue
+				}
+				if status.State.Waiting != nil {
+					continue // nothing to stream yet
+				}
+				key := fmt.Sprintf("%s/%s", pod.UID, status.Name)
+				mu.Lock()
+				restartCount, ok := streamed[key]
+				since := interrupted[key]
+				mu.Unlock()
+				if ok && restartCount >= status.RestartCount {
+					continue // already streaming or streamed this instance
+				}
+				containerOptions := options
+				containerOptions.Container = status.Name
+				stream, reader, err := c.streamLogs(ctx, NamespacedName(pod), containerOptions, since)
+				if err != nil {
+					continue // retried with the next poll
+				}
+				mu.Lock()
+				streamed[key] = status.RestartCount
+				delete(interrupted, key)
+				mu.Unlock()
+				wg.Add(1)
+				go func(key string, stream io.ReadCloser, reader *logReader, prefix string) {
+					defer wg.Done()
+					defer stream.Close()
+					err := copyLogs(reader, prefix, out)
+					if err != nil && err != io.EOF && ctx.Err() == nil {
+						// Resumed with the next poll
+						mu.Lock()
+						delete(streamed, key)
+						interrupted[key] = reader.since
+						mu.Unlock()
+					}
+				}(key, stream, reader, fmt.Sprintf("%s/%s ", pod.Name, status.Name))
+			}
+		}
+		timer := time.NewTimer(c.recheckInterval())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+	}
+}
+
+// streamLogs opens a log stream of the pod, which continues after since if
+// it is set, e.g. to resume an interrupted stream. Timestamps are always
+// requested, but only kept in the lines returned by the reader if requested
+// by the options.
+func (c *Client) streamLogs(ctx context.Context, pod types.NamespacedName, options corev1.PodLogOptions, since time.Time) (io.ReadCloser, *logReader, error) {
+	timestamps := options.Timestamps
+	options.Timestamps = true
+	if !since.IsZero() {
+		sinceTime := metav1.NewTime(since)
+		options.SinceTime = &sinceTime
+		options.TailLines = nil
+	}
+	stream, err := c.Clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &options).Stream(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return stream, &logReader{
+		reader:     bufio.NewReader(stream),
+		since:      since,
+		timestamps: timestamps,
+	}, nil
+}
+
+// logReader reads the lines of a log stream retrieved with timestamps. Lines
+// not written after since are skipped, as SinceTime is only precise to the
+// second.
+type logReader struct {
+	reader     *bufio.Reader
+	since      time.Time // timestamp of the last line read
+	timestamps bool      // keep the timestamps of the lines
+}
+
+// ReadLine returns the next line without the trailing newline. The error is
+// io.EOF once the stream ended.
+func (r *logReader) ReadLine() (string, error) {
+	for {
+		line, err := r.reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return "", err // partial lines are read again once resumed
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if len(line) > 0 {
+			timestamp, text := splitTimestamp(line)
+			if timestamp.IsZero() || timestamp.After(r.since) {
+				if !timestamp.IsZero() {
+					r.since = timestamp
+				}
+				if r.timestamps {
+					return line, nil
+				}
+				return text, nil
+			}
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+}
+
+// splitTimestamp splits a log line into its RFC3339 timestamp and the actual
+// text. A zero time is returned if the line is not prefixed by a timestamp.
+func splitTimestamp(line string) (time.Time, string) {
+	i := strings.IndexByte(line, ' ')
+	if i < 0 {
+		return time.Time{}, line
+	}
+	timestamp, err := time.Parse(time.RFC3339Nano, line[:i])
+	if err != nil {
+		return time.Time{}, line
+	}
+	return timestamp, line[i+1:]
+}
+
+// copyLogs writes the lines of the reader prefixed to out until the stream
+// ends, which is reported as io.EOF. Errors writing to out stop the copy, but
+// are not returned, so only interrupted streams result in an error.
+func copyLogs(reader *logReader, prefix string, out *prefixWriter) error {
+	for {
+		line, err := reader.ReadLine()
+		if err != nil {
+			return err
+		}
+		if err := out.WriteLine(prefix, line); err != nil {
+			return nil
+		}
+	}
+}
+
+// maxWaitErrorLogs limits the number of log lines attached to a WaitError.
+const maxWaitErrorLogs = 20
+
+// WaitForLog follows the logs of the pod until a line contains the substring
+// and returns the line. Container restarts are handled by following the new
+// instance of the container. LogsWithContainer has to be used for pods with
+// multiple containers, while LogsWithFollow is always set.
+//
+// If the context is done before a line matched, a *WaitError is returned,
+// which contains the most recent log lines.
+func (c *Client) WaitForLog(ctx context.Context, pod *corev1.Pod, substr string, opts ...LogsOption) (string, error) {
+	return c.waitForLog(ctx, pod, fmt.Sprintf("PodLogContains(%q)", substr), func(line string) bool {
+		return strings.Contains(line, substr)
+	}, opts...)
+}
+
+// WaitForLogRegexp follows the logs of the pod until a line matches the
+// regular expression similar to WaitForLog.
+func (c *Client) WaitForLogRegexp(ctx context.Context, pod *corev1.Pod, re *regexp.Regexp, opts ...LogsOption) (string, error) {
+	return c.waitForLog(ctx, pod, fmt.Sprintf("PodLogMatches(%q)", re), re.MatchString, opts...)
+}
+
+func (c *Client) waitForLog(ctx context.Context, pod *corev1.Pod, description string, match func(string) bool, opts ...LogsOption) (string, error) {
+	options := corev1.PodLogOptions{}
+	for _, opt := range opts {
+		opt.apply(&options)
+	}
+	options.Follow = true
+	options.Previous = false
+	current := pod.DeepCopy()
+	tail := []string{}
+	streamedRestarts := int32(-1)
+	var since time.Time // last line of an interrupted stream
+	backoff := c.pollBackoff()
+	for {
+		err := c.Get(ctx, NamespacedName(pod), current)
+		if apierrors.IsNotFound(err) {
+			return "", err
+		}
+		if err == nil {
+			if options.Container == "" && len(current.Spec.Containers) == 1 {
+				options.Container = current.Spec.Containers[0].Name
+			}
+			if options.Container == "" {
+				return "", fmt.Errorf("pod %s/%s has multiple containers, but none was specified", pod.Namespace, pod.Name)
+			}
+			status := getContainerStatus(current, options.Container)
+			if status == nil && len(current.Status.ContainerStatuses) > 0 {
+				return "", fmt.Errorf("pod %s/%s does not have container %s", pod.Namespace, pod.Name, options.Container)
+			}
+			// Every instance of the container is only streamed once until
+			// it ended. Interrupted streams are resumed after the last line.
+			if status != nil && status.State.Waiting == nil && status.RestartCount != streamedRestarts {
+				line, err := c.matchLogs(ctx, NamespacedName(pod), options, match, &since, &tail)
+				if err == nil {
+					return line, nil
+				} else if err == io.EOF {
+					streamedRestarts = status.RestartCount
+					since = time.Time{}
+				}
+			}
+		}
+		timer := time.NewTimer(backoff.Step())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			waitErr := c.newWaitError(ConditionFunc(current, description, func() bool {
+				return false
+			}), ctx.Err())
+			waitErr.Logs = tail
+			return "", waitErr
+		case <-timer.C:
+		}
+	}
+}
+
+// matchLogs streams the logs after since until a line matches, while the
+// most recent lines are kept in tail and since is advanced to the last line
+// read. If no line matched, the error of the stream is returned, which is
+// io.EOF if the container terminated.
+func (c *Client) matchLogs(ctx context.Context, pod types.NamespacedName, options corev1.PodLogOptions, match func(string) bool, since *time.Time, tail *[]string) (string, error) {
+	stream, reader, err := c.streamLogs(ctx, pod, options, *since)
+	if err != nil {
+		return "", err
+	}
+	defer stream.Close()
+	defer func() {
+		*since = reader.since
+	}()
+	for {
+		line, err := reader.ReadLine()
+		if err != nil {
+			return "", err
+		}
+		if match(line) {
+			return line, nil
+		}
+		*tail = append(*tail, line)
+		if len(*tail) > maxWaitErrorLogs {
+			*tail = (*tail)[len(*tail)-maxWaitErrorLogs:]
+		}
+	}
+}
+
+func getContainerStatus(pod *corev1.Pod, container string) *corev1.ContainerStatus {
+	for i := range pod.Status.ContainerStatuses {
+		if pod.Status.ContainerStatuses[i].Name == container {
+			return &pod.Status.ContainerStatuses[i]
+		}
+	}
+	return nil
+}
+
+// prefixWriter writes prefixed lines, so lines of concurrent streams are not
+// interleaved.
+type prefixWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (p *prefixWriter) WriteLine(prefix, line string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !strings.HasSuffix(line, "\n") {
+		line += "\n"
+	}
+	_, err := io.WriteString(p.w, prefix+line)
+	return err
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2020 Testutil Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/kubism/testutil/pkg/rand"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+// mustCreateLoggingPod creates a pod running the shell script, which can
+// persist files across container restarts in /data.
+func mustCreateLoggingPod(script string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "logging-" + rand.String(5),
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name:    "logging",
+				Image:   "nginx:alpine",
+				Command: []string{"sh", "-c", script},
+				VolumeMounts: []corev1.VolumeMount{{
+					Name:      "data",
+					MountPath: "/data",
+				}},
+			}},
+			Volumes: []corev1.Volume{{
+				Name: "data",
+				VolumeSource: corev1.VolumeSource{
+					EmptyDir: &corev1.EmptyDirVolumeSource{},
+				},
+			}},
+		},
+	}
+	gomega.Expect(k8sClient.Create(context.Background(), pod)).To(gomega.Succeed())
+	return pod
+}
+
+var _ = Describe("Logs", func() {
+	It("can get logs of existing pod", func() {
+		pod := mustGetReadyNginxPod(nginxRelease)
+		logs, err := k8sClient.LogsString(context.Background(), pod)
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		gomega.Expect(len(logs)).To(gomega.BeNumerically(">", 0))
+	})
+	It("fails if pod does not exist", func() {
+		var pod corev1.Pod
+		pod.Namespace = "default"
+		pod.Name = "doesnotexist"
+		_, err := k8sClient.LogsString(context.Background(), &pod)
+		gomega.Expect(err).To(gomega.HaveOccurred())
+	})
+	It("respects options", func() {
+		pod := mustGetReadyNginxPod(nginxRelease)
+		container := pod.Spec.Containers[0].Name
+		logs, err := k8sClient.LogsString(context.Background(), pod,
+			LogsWithContainer(container), LogsWithTailLines(1), LogsWithTimestamps())
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		lines := strings.Split(strings.TrimSpace(logs), "\n")
+		gomega.Expect(lines).To(gomega.HaveLen(1))
+		_, err = time.Parse(time.RFC3339Nano, strings.Fields(lines[0])[0])
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		logs, err = k8sClient.LogsString(context.Background(), pod,
+			LogsWithSinceTime(time.Now().Add(time.Hour)))
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		gomega.Expect(logs).To(gomega.BeEmpty())
+	})
+	It("fails for unknown container", func() {
+		pod := mustGetReadyNginxPod(nginxRelease)
+		_, err := k8sClient.LogsString(context.Background(), pod, LogsWithContainer("doesnotexist"))
+		gomega.Expect(err).To(gomega.HaveOccurred())
+	})
+})
+
+var _ = Describe("LogsForSelector", func() {
+	It("streams logs of all matching pods with prefix", func() {
+		pod := mustGetReadyNginxPod(nginxRelease)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		out := &syncBuffer{}
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- k8sClient.LogsForSelector(ctx, pod.Namespace,
+				labels.SelectorFromSet(labels.Set{"app.kubernetes.io/instance": nginxRelease.Name}), out)
+		}()
+		prefix := pod.Name + "/" + pod.Spec.Containers[0].Name + " "
+		gomega.Eventually(out.String, timeout).Should(gomega.ContainSubstring(prefix))
+		for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+			gomega.Expect(line).To(gomega.HavePrefix(prefix))
+		}
+		cancel()
+		gomega.Eventually(errCh, timeout).Should(gomega.Receive(gomega.BeNil()))
+	})
+})
+
+var _ = Describe("WaitForLog", func() {
+	It("returns matching line", func() {
+		pod := mustCreateLoggingPod("echo starting; sleep 1; echo listening on :8080; sleep 3600")
+		defer k8sClient.Delete(context.Background(), pod) // nolint:errcheck
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		line, err := k8sClient.WaitForLog(ctx, pod, "listening on")
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		gomega.Expect(line).To(gomega.Equal("listening on :8080"))
+		line, err = k8sClient.WaitForLogRegexp(ctx, pod, regexp.MustCompile(`on :\d+$`))
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		gomega.Expect(line).To(gomega.Equal("listening on :8080"))
+	})
+	It("follows restarted container", func() {
+		pod := mustCreateLoggingPod("if [ -f /data/crashed ]; then echo ready; sleep 3600; " +
+			"else touch /data/crashed; echo crashing; exit 1; fi")
+		defer k8sClient.Delete(context.Background(), pod) // nolint:errcheck
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		line, err := k8sClient.WaitForLog(ctx, pod, "ready")
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		gomega.Expect(line).To(gomega.Equal("ready"))
+	})
+	It("returns recent logs on timeout", func() {
+		pod := mustCreateLoggingPod("echo starting; sleep 3600")
+		defer k8sClient.Delete(context.Background(), pod) // nolint:errcheck
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		defer cancel()
+		_, err := k8sClient.WaitForLog(ctx, pod, "never logged")
+		var waitErr *WaitError
+		gomega.Expect(errors.As(err, &waitErr)).To(gomega.Equal(true))
+		gomega.Expect(waitErr.Logs).To(gomega.Equal([]string{"starting"}))
+		gomega.Expect(waitErr.Error()).To(gomega.ContainSubstring("PodLogContains"))
+	})
+	It("fails if pod does not exist", func() {
+		var pod corev1.Pod
+		pod.Namespace = "default"
+		pod.Name = "doesnotexist"
+		_, err := k8sClient.WaitForLog(context.Background(), &pod, "never logged")
+		gomega.Expect(err).To(gomega.HaveOccurred())
+	})
+})
+
+var _ = Describe("logReader", func() {
+	It("skips lines already read when resuming", func() {
+		since, err := time.Parse(time.RFC3339Nano, "2020-01-01T00:00:01.5Z")
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		reader := &logReader{
+			reader: bufio.NewReader(strings.NewReader("2020-01-01T00:00:01.5Z a\n" +
+				"2020-01-01T00:00:02Z b\n2020-01-01T00:00:03Z c")),
+			since: since,
+		}
+		line, err := reader.ReadLine()
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		gomega.Expect(line).To(gomega.Equal("b"))
+		line, err = reader.ReadLine()
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		gomega.Expect(line).To(gomega.Equal("c"))
+		gomega.Expect(reader.since).To(gomega.Equal(since.Add(1500 * time.Millisecond)))
+		_, err = reader.ReadLine()
+		gomega.Expect(err).To(gomega.Equal(io.EOF))
+	})
+})
@@ -0,0 +1,308 @@
+/*
+Copyright 2020 Testutil Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// manifestExtensions are the extensions of files read from directories.
+var manifestExtensions = map[string]bool{".yaml": true, ".yml": true, ".json": true}
+
+// ManifestSource interface is implemented by all possible sources of
+// manifests for ApplyManifests.
+type ManifestSource interface {
+	documents() ([][]byte, error)
+}
+
+type manifestSourceAdapter func() ([][]byte, error)
+
+func (s manifestSourceAdapter) documents() ([][]byte, error) {
+	return s()
+}
+
+// ManifestsFromPath reads the manifests of the file. If path is a directory,
+// all `.yaml`, `.yml` and `.json` files within it and its subdirectories are
+// read in lexical order.
+func ManifestsFromPath(path string) ManifestSource {
+	return manifestSourceAdapter(func() ([][]byte, error) {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		files := []string{path}
+		if info.IsDir() {
+			files = []string{}
+			err := filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				if info.Mode().IsRegular() && manifestExtensions[filepath.Ext(file)] {
+					files = append(files, file)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			sort.Strings(files)
+		}
+		documents := [][]byte{}
+		for _, file := range files {
+			f, err := os.Open(file)
+			if err != nil {
+				return nil, err
+			}
+			fileDocuments, err := splitManifests(f)
+			f.Close()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read manifests of %s: %w", file, err)
+			}
+			documents = append(documents, fileDocuments...)
+		}
+		return documents, nil
+	})
+}
+
+// ManifestsFromReader reads the manifests of the reader.
+func ManifestsFromReader(r io.Reader) ManifestSource {
+	return manifestSourceAdapter(func() ([][]byte, error) {
+		return splitManifests(r)
+	})
+}
+
+// ManifestsFromString reads the manifests of the string.
+func ManifestsFromString(manifests string) ManifestSource {
+	return ManifestsFromReader(strings.NewReader(manifests))
+}
+
+// splitManifests splits the `---`-separated YAML or JSON documents and
+// converts them to JSON. Empty documents are skipped.
+func splitManifests(r io.Reader) ([][]byte, error) {
+	reader := yaml.NewYAMLReader(bufio.NewReader(r))
+	documents := [][]byte{}
+	for {
+		document, err := reader.Read()
+		if err == io.EOF {
+			return documents, nil
+		} else if err != nil {
+			return nil, err
+		}
+		document, err = yaml.ToJSON(document)
+		if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(document)) == 0 || bytes.Equal(bytes.TrimSpace(document), []byte("null")) {
+			continue
+		}
+		documents = append(documents, document)
+	}
+}
+
+// manifest is a decoded document, which still has its original JSON
+// representation to update an existing object.
+type manifest struct {
+	obj  runtime.Object
+	raw  []byte
+	kind schema.GroupKind
+}
+
+// ApplyManifests creates the objects of all manifests or updates them if they
+// already exist. Objects are decoded into typed objects if the scheme of the
+// client knows their kind and into unstructured.Unstructured otherwise. Lists
+// are expanded into their items. Namespaces and CustomResourceDefinitions are
+// applied first, while waiting for the latter to be established, so custom
+// resources can be part of the same manifests.
+//
+// Existing objects are updated using their manifest as JSON merge patch, so
+// fields of the manifest overwrite the fields of the object, while lists are
+// replaced as a whole. Fields missing in the manifest are kept, which also
+// means fields removed from a manifest are not removed from the object.
+//
+// The returned objects are in the order they were applied and can be removed
+// using DeleteManifests. Namespaced objects without namespace are created in
+// the `default` namespace.
+func (c *Client) ApplyManifests(ctx context.Context, sources ...ManifestSource) ([]runtime.Object, error) {
+	manifests := []manifest{}
+	for _, source := range sources {
+		documents, err := source.documents()
+		if err != nil {
+			return nil, err
+		}
+		for _, document := range documents {
+			decoded, err := c.decodeManifest(document)
+			if err != nil {
+				return nil, err
+			}
+			manifests = append(manifests, decoded...)
+		}
+	}
+	sort.SliceStable(manifests, func(i, j int) bool {
+		return manifestPriority(manifests[i].kind) < manifestPriority(manifests[j].kind)
+	})
+	applied := []runtime.Object{}
+	for _, m := range manifests {
+		if err := c.applyManifest(ctx, m); err != nil {
+			return applied, err
+		}
+		applied = append(applied, m.obj)
+		if m.kind == crdGroupKind {
+			if err := c.WaitUntil(ctx, crdIsEstablished(m.obj)); err != nil {
+				return applied, err
+			}
+		}
+	}
+	return applied, nil
+}
+
+// DeleteManifests deletes the objects returned by ApplyManifests in reverse
+// order and waits until all of them are deleted.
+func (c *Client) DeleteManifests(ctx context.Context, objs []runtime.Object) error {
+	return c.deleteInReverse(ctx, objs)
+}
+
+// deleteInReverse deletes the objects in reverse order, waits until all of
+// them are deleted and aggregates all errors.
+func (c *Client) deleteInReverse(ctx context.Context, objs []runtime.Object, opts ...client.DeleteOption) error {
+	errs := []error{}
+	deleted := []runtime.Object{}
+	for i := len(objs) - 1; i >= 0; i-- {
+		if err := c.Delete(ctx, objs[i], opts...); err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, err)
+			continue
+		}
+		deleted = append(deleted, objs[i])
+	}
+	for _, obj := range deleted {
+		if _, err := c.WaitUntilDeleted(ctx, obj); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+var (
+	namespaceGroupKind = schema.GroupKind{Kind: "Namespace"}
+	crdGroupKind       = schema.GroupKind{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}
+)
+
+func manifestPriority(kind schema.GroupKind) int {
+	switch kind {
+	case namespaceGroupKind:
+		return 0
+	case crdGroupKind:
+		return 1
+	}
+	return 2
+}
+
+// decodeManifest decodes the JSON document into typed objects, if known by
+// the scheme, or unstructured objects.
+func (c *Client) decodeManifest(document []byte) ([]manifest, error) {
+	obj, err := runtime.Decode(unstructured.UnstructuredJSONScheme, document)
+	if err != nil {
+		return nil, err
+	}
+	if list, ok := obj.(*unstructured.UnstructuredList); ok {
+		manifests := []manifest{}
+		for i := range list.Items {
+			raw, err := list.Items[i].MarshalJSON()
+			if err != nil {
+				return nil, err
+			}
+			decoded, err := c.decodeManifest(raw)
+			if err != nil {
+				return nil, err
+			}
+			manifests = append(manifests, decoded...)
+		}
+		return manifests, nil
+	}
+	u := obj.(*unstructured.Unstructured)
+	gvk := u.GroupVersionKind()
+	m := manifest{obj: u, raw: document, kind: gvk.GroupKind()}
+	if typed, err := c.scheme.New(gvk); err == nil {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, typed); err != nil {
+			return nil, err
+		}
+		typed.GetObjectKind().SetGroupVersionKind(gvk)
+		m.obj = typed
+	}
+	return []manifest{m}, nil
+}
+
+// applyManifest creates the object or, if it already exists, updates it
+// using the manifest as merge patch, so fields not part of the manifest are
+// kept, e.g. the cluster IP of a service.
+func (c *Client) applyManifest(ctx context.Context, m manifest) error {
+	accessor, err := meta.Accessor(m.obj)
+	if err != nil {
+		return err
+	}
+	if accessor.GetNamespace() == "" {
+		gvk := m.obj.GetObjectKind().GroupVersionKind()
+		mapping, err := c.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			return err
+		}
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			accessor.SetNamespace("default")
+		}
+	}
+	err = c.Create(ctx, m.obj)
+	if !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	return c.Patch(ctx, m.obj, client.RawPatch(types.MergePatchType, m.raw))
+}
+
+// crdIsEstablished waits until the CustomResourceDefinition is established,
+// so its custom resources can be created.
+func crdIsEstablished(crd runtime.Object) Condition {
+	return ConditionFunc(crd, "CRDIsEstablished", func() bool {
+		content, err := toUnstructuredContent(crd)
+		if err != nil {
+			return false
+		}
+		conditions, _, _ := unstructured.NestedSlice(content, "status", "conditions")
+		for _, c := range conditions {
+			condition, ok := c.(map[string]interface{})
+			if ok && condition["type"] == "Established" && condition["status"] == "True" {
+				return true
+			}
+		}
+		return false
+	})
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2020 Testutil Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/kubism/testutil/pkg/fs"
+	"github.com/kubism/testutil/pkg/rand"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+const testManifests = `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cm
+  namespace: %[1]s
+data:
+  foo: bar
+---
+# namespaces are applied first
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: %[1]s
+---
+`
+
+const testCRDManifests = `
+apiVersion: stable.example.com/v1
+kind: CronTab
+metadata:
+  name: crontab
+spec:
+  cronSpec: "* * * * */5"
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: crontabs.stable.example.com
+spec:
+  group: stable.example.com
+  versions:
+  - name: v1
+    served: true
+    storage: true
+    schema:
+      openAPIV3Schema:
+        type: object
+        x-kubernetes-preserve-unknown-fields: true
+  scope: Namespaced
+  names:
+    plural: crontabs
+    singular: crontab
+    kind: CronTab
+`
+
+var _ = Describe("ApplyManifests", func() {
+	It("applies namespaces first and decodes typed objects", func() {
+		namespace := "ns-" + rand.String(5)
+		objs, err := k8sClient.ApplyManifests(context.Background(),
+			ManifestsFromString(fmt.Sprintf(testManifests, namespace)))
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		gomega.Expect(objs).To(gomega.HaveLen(2))
+		gomega.Expect(objs[0]).To(gomega.BeAssignableToTypeOf(&corev1.Namespace{}))
+		gomega.Expect(objs[1]).To(gomega.BeAssignableToTypeOf(&corev1.ConfigMap{}))
+		gomega.Expect(objs[1].(*corev1.ConfigMap).UID).ToNot(gomega.BeEmpty())
+		gomega.Expect(k8sClient.DeleteManifests(context.Background(), objs)).To(gomega.Succeed())
+	})
+	It("updates existing objects", func() {
+		namespace := "ns-" + rand.String(5)
+		manifests := fmt.Sprintf(testManifests, namespace)
+		objs, err := k8sClient.ApplyManifests(context.Background(), ManifestsFromString(manifests))
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		defer k8sClient.DeleteManifests(context.Background(), objs) // nolint:errcheck
+		configMap := objs[1].(*corev1.ConfigMap)
+		configMap.Data["foo"] = "changed"
+		gomega.Expect(k8sClient.Update(context.Background(), configMap)).To(gomega.Succeed())
+		_, err = k8sClient.ApplyManifests(context.Background(), ManifestsFromString(manifests))
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		gomega.Expect(k8sClient.Get(context.Background(), NamespacedName(configMap), configMap)).To(gomega.Succeed())
+		gomega.Expect(configMap.Data["foo"]).To(gomega.Equal("bar"))
+	})
+	It("reads directories", func() {
+		namespace := "ns-" + rand.String(5)
+		td, err := fs.NewTempDir()
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		defer td.Close()
+		gomega.Expect(os.MkdirAll(filepath.Join(td.Path, "sub"), 0755)).To(gomega.Succeed())
+		gomega.Expect(ioutil.WriteFile(filepath.Join(td.Path, "sub", "manifests.yaml"),
+			[]byte(fmt.Sprintf(testManifests, namespace)), 0644)).To(gomega.Succeed())
+		gomega.Expect(ioutil.WriteFile(filepath.Join(td.Path, "README.md"), []byte("# ignored"), 0644)).To(gomega.Succeed())
+		objs, err := k8sClient.ApplyManifests(context.Background(), ManifestsFromPath(td.Path))
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		gomega.Expect(objs).To(gomega.HaveLen(2))
+		gomega.Expect(k8sClient.DeleteManifests(context.Background(), objs)).To(gomega.Succeed())
+	})
+	It("applies custom resources after their definition", func() {
+		objs, err := k8sClient.ApplyManifests(context.Background(), ManifestsFromString(testCRDManifests))
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		gomega.Expect(objs).To(gomega.HaveLen(2))
+		gomega.Expect(objs[1]).To(gomega.BeAssignableToTypeOf(&unstructured.Unstructured{}))
+		gomega.Expect(objs[1].(*unstructured.Unstructured).GetNamespace()).To(gomega.Equal("default"))
+		gomega.Expect(k8sClient.DeleteManifests(context.Background(), objs)).To(gomega.Succeed())
+	})
+	It("fails for invalid manifests", func() {
+		_, err := k8sClient.ApplyManifests(context.Background(), ManifestsFromString("kind: [}"))
+		gomega.Expect(err).To(gomega.HaveOccurred())
+		_, err = k8sClient.ApplyManifests(context.Background(), ManifestsFromPath("/doesnotexist"))
+		gomega.Expect(err).To(gomega.HaveOccurred())
+	})
+})
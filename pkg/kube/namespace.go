@@ -0,0 +1,228 @@
+/*
+Copyright 2020 Testutil Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kubism/testutil/pkg/rand"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type testNamespaceOptions struct {
+	Labels        map[string]string
+	ResourceQuota *corev1.ResourceQuotaSpec
+	LimitRange    *corev1.LimitRangeSpec
+	DeleteTimeout time.Duration
+}
+
+// TestNamespaceOption interface is implemented by all possible options to
+// create a test namespace.
+type TestNamespaceOption interface {
+	apply(*testNamespaceOptions)
+}
+
+type testNamespaceOptionAdapter func(*testNamespaceOptions)
+
+func (c testNamespaceOptionAdapter) apply(o *testNamespaceOptions) {
+	c(o)
+}
+
+// TestNamespaceWithLabels adds the labels to the namespace.
+func TestNamespaceWithLabels(labels map[string]string) TestNamespaceOption {
+	return testNamespaceOptionAdapter(func(o *testNamespaceOptions) {
+		o.Labels = labels
+	})
+}
+
+// TestNamespaceWithResourceQuota creates a ResourceQuota with the spec in the
+// namespace.
+func TestNamespaceWithResourceQuota(spec corev1.ResourceQuotaSpec) TestNamespaceOption {
+	return testNamespaceOptionAdapter(func(o *testNamespaceOptions) {
+		o.ResourceQuota = &spec
+	})
+}
+
+// TestNamespaceWithLimitRange creates a LimitRange with the spec in the
+// namespace, e.g. to provide default resources required by a ResourceQuota.
+func TestNamespaceWithLimitRange(spec corev1.LimitRangeSpec) TestNamespaceOption {
+	return testNamespaceOptionAdapter(func(o *testNamespaceOptions) {
+		o.LimitRange = &spec
+	})
+}
+
+// TestNamespaceWithDeleteTimeout sets the duration Close waits for the
+// namespace to be terminated. The default is two minutes.
+func TestNamespaceWithDeleteTimeout(timeout time.Duration) TestNamespaceOption {
+	return testNamespaceOptionAdapter(func(o *testNamespaceOptions) {
+		o.DeleteTimeout = timeout
+	})
+}
+
+// TestNamespace is a randomly named namespace, which isolates the objects of
+// a single test.
+type TestNamespace struct {
+	// Name of the namespace
+	Name string
+	// Namespace is the created namespace.
+	Namespace *corev1.Namespace
+
+	client  *Client
+	options testNamespaceOptions
+}
+
+// NewTestNamespace creates a namespace named after the prefix with a random
+// suffix. Make sure to always call Close once the namespace is not required
+// anymore.
+func (c *Client) NewTestNamespace(ctx context.Context, prefix string, opts ...TestNamespaceOption) (*TestNamespace, error) {
+	options := testNamespaceOptions{ // Default options
+		DeleteTimeout: 2 * time.Minute,
+	}
+	for _, opt := range opts {
+		opt.apply(&options)
+	}
+	if prefix == "" {
+		prefix = "test"
+	}
+	namespace := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   fmt.Sprintf("%s-%s", prefix, rand.String(5)),
+			Labels: options.Labels,
+		},
+	}
+	if err := c.Create(ctx, namespace); err != nil {
+		return nil, err
+	}
+	ns := &TestNamespace{
+		Name:      namespace.Name,
+		Namespace: namespace,
+		client:    c,
+		options:   options,
+	}
+	if options.ResourceQuota != nil {
+		err := c.Create(ctx, &corev1.ResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ns.Name, Name: "quota"},
+			Spec:       *options.ResourceQuota,
+		})
+		if err != nil {
+			ns.Close() // nolint:errcheck
+			return nil, err
+		}
+	}
+	if options.LimitRange != nil {
+		err := c.Create(ctx, &corev1.LimitRange{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ns.Name, Name: "limits"},
+			Spec:       *options.LimitRange,
+		})
+		if err != nil {
+			ns.Close() // nolint:errcheck
+			return nil, err
+		}
+	}
+	return ns, nil
+}
+
+// Close deletes the namespace and waits until it is terminated. If it was not
+// terminated in time, a *NamespaceTerminationError is returned, which lists
+// the objects still present in the namespace.
+func (ns *TestNamespace) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), ns.options.DeleteTimeout)
+	defer cancel()
+	// Deleting a namespace, which is already terminating, results in a
+	// conflict, so Close can be retried after it timed out.
+	err := ns.client.Delete(ctx, ns.Namespace)
+	if err != nil && !apierrors.IsNotFound(err) && !apierrors.IsConflict(err) {
+		return err
+	}
+	_, err = ns.client.WaitUntilDeleted(ctx, ns.Namespace)
+	if waitErr, ok := err.(*WaitError); ok {
+		return &NamespaceTerminationError{
+			Namespace: ns.Name,
+			Remaining: ns.client.remainingObjects(ns.Name),
+			Err:       waitErr,
+		}
+	}
+	return err
+}
+
+// RemainingObject describes an object still present in a namespace, which
+// is being terminated.
+type RemainingObject struct {
+	// Kind of the object.
+	Kind string
+	// Name of the object.
+	Name string
+	// Finalizers still present, which usually block the deletion.
+	Finalizers []string
+}
+
+// NamespaceTerminationError is returned if a namespace was not terminated in
+// time.
+type NamespaceTerminationError struct {
+	// Namespace, which was not terminated.
+	Namespace string
+	// Remaining are the objects still present in the namespace.
+	Remaining []RemainingObject
+	// Err is the error returned while waiting for the termination.
+	Err *WaitError
+}
+
+func (e *NamespaceTerminationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "namespace %s was not terminated", e.Namespace)
+	if len(e.Remaining) > 0 {
+		b.WriteString("\nremaining objects:")
+		for _, obj := range e.Remaining {
+			fmt.Fprintf(&b, "\n  %s %s", obj.Kind, obj.Name)
+			if len(obj.Finalizers) > 0 {
+				fmt.Fprintf(&b, " (blocked by finalizers: %s)", strings.Join(obj.Finalizers, ", "))
+			}
+		}
+	}
+	fmt.Fprintf(&b, "\n%v", e.Err)
+	return b.String()
+}
+
+func (e *NamespaceTerminationError) Unwrap() error {
+	return e.Err
+}
+
+// remainingObjects lists all objects in the namespace. As the original
+// context is already done, a separate short-lived context is used.
+func (c *Client) remainingObjects(namespace string) []RemainingObject {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	objs, err := c.listObjects(ctx, namespace)
+	if err != nil {
+		return nil
+	}
+	remaining := []RemainingObject{}
+	for _, obj := range objs {
+		remaining = append(remaining, RemainingObject{
+			Kind:       obj.GetKind(),
+			Name:       obj.GetName(),
+			Finalizers: obj.GetFinalizers(),
+		})
+	}
+	return remaining
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2020 Testutil Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+var _ = Describe("NewTestNamespace", func() {
+	It("creates namespace with quota and limits", func() {
+		ns, err := k8sClient.NewTestNamespace(context.Background(), "quota",
+			TestNamespaceWithLabels(map[string]string{"test": "true"}),
+			TestNamespaceWithResourceQuota(corev1.ResourceQuotaSpec{
+				Hard: corev1.ResourceList{corev1.ResourcePods: resource.MustParse("2")},
+			}),
+			TestNamespaceWithLimitRange(corev1.LimitRangeSpec{
+				Limits: []corev1.LimitRangeItem{{
+					Type:           corev1.LimitTypeContainer,
+					DefaultRequest: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("10m")},
+				}},
+			}))
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		gomega.Expect(strings.HasPrefix(ns.Name, "quota-")).To(gomega.BeTrue())
+		namespace := &corev1.Namespace{}
+		gomega.Expect(k8sClient.Get(context.Background(), client.ObjectKey{Name: ns.Name}, namespace)).To(gomega.Succeed())
+		gomega.Expect(namespace.Labels).To(gomega.HaveKeyWithValue("test", "true"))
+		quotaList := &corev1.ResourceQuotaList{}
+		gomega.Expect(k8sClient.List(context.Background(), quotaList, client.InNamespace(ns.Name))).To(gomega.Succeed())
+		gomega.Expect(quotaList.Items).To(gomega.HaveLen(1))
+		limitRangeList := &corev1.LimitRangeList{}
+		gomega.Expect(k8sClient.List(context.Background(), limitRangeList, client.InNamespace(ns.Name))).To(gomega.Succeed())
+		gomega.Expect(limitRangeList.Items).To(gomega.HaveLen(1))
+		gomega.Expect(ns.Close()).To(gomega.Succeed())
+		err = k8sClient.Get(context.Background(), client.ObjectKey{Name: ns.Name}, namespace)
+		gomega.Expect(apierrors.IsNotFound(err)).To(gomega.BeTrue())
+	})
+	It("reports objects stuck in finalization", func() {
+		ns, err := k8sClient.NewTestNamespace(context.Background(), "",
+			TestNamespaceWithDeleteTimeout(5*time.Second))
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:  ns.Name,
+				Name:       "stuck",
+				Finalizers: []string{testFinalizer},
+			},
+		}
+		gomega.Expect(k8sClient.Create(context.Background(), configMap)).To(gomega.Succeed())
+		err = ns.Close()
+		gomega.Expect(err).To(gomega.HaveOccurred())
+		var terminationErr *NamespaceTerminationError
+		gomega.Expect(errors.As(err, &terminationErr)).To(gomega.BeTrue())
+		gomega.Expect(terminationErr.Remaining).To(gomega.ContainElement(RemainingObject{
+			Kind:       "ConfigMap",
+			Name:       "stuck",
+			Finalizers: []string{testFinalizer},
+		}))
+		gomega.Expect(k8sClient.Patch(context.Background(), configMap, removeFinalizersPatch)).To(gomega.Succeed())
+		gomega.Expect(ns.Close()).To(gomega.Succeed())
+	})
+})
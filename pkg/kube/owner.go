@@ -0,0 +1,169 @@
+/*
+Copyright 2020 Testutil Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/discovery"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ListForOwner lists all objects in the namespace of the owner, which are
+// directly owned by it. Any typed list, e.g. corev1.PodList, or
+// unstructured.UnstructuredList with its kind set can be used.
+func (c *Client) ListForOwner(ctx context.Context, list runtime.Object, owner runtime.Object) error {
+	accessor, err := meta.Accessor(owner)
+	if err != nil {
+		return err
+	}
+	if accessor.GetUID() == "" {
+		return fmt.Errorf("owner uid can not be empty")
+	}
+	err = c.List(ctx, list, client.InNamespace(accessor.GetNamespace()))
+	if err != nil {
+		return err
+	}
+	return reduceObjectsByOwner(list, accessor.GetUID())
+}
+
+// Descendants walks the ownership graph starting at the owner and returns all
+// objects directly or transitively owned by it, e.g. the ReplicaSets and Pods
+// of a Deployment. All resource types discoverable and listable by the
+// client are taken into account. The objects are ordered breadth-first, so
+// owners always precede the objects they own.
+func (c *Client) Descendants(ctx context.Context, owner runtime.Object) ([]*unstructured.Unstructured, error) {
+	accessor, err := meta.Accessor(owner)
+	if err != nil {
+		return nil, err
+	}
+	if accessor.GetUID() == "" {
+		return nil, fmt.Errorf("owner uid can not be empty")
+	}
+	objs, err := c.listObjects(ctx, accessor.GetNamespace())
+	if err != nil {
+		return nil, err
+	}
+	children := map[types.UID][]*unstructured.Unstructured{}
+	for _, obj := range objs {
+		for _, ref := range obj.GetOwnerReferences() {
+			children[ref.UID] = append(children[ref.UID], obj)
+		}
+	}
+	descendants := []*unstructured.Unstructured{}
+	visited := sets.NewString(string(accessor.GetUID()))
+	queue := []types.UID{accessor.GetUID()}
+	for len(queue) > 0 {
+		uid := queue[0]
+		queue = queue[1:]
+		for _, child := range children[uid] {
+			if visited.Has(string(child.GetUID())) {
+				continue // owned by several objects of the tree
+			}
+			visited.Insert(string(child.GetUID()))
+			descendants = append(descendants, child)
+			queue = append(queue, child.GetUID())
+		}
+	}
+	return descendants, nil
+}
+
+// listObjects lists the objects of all listable resources in the namespace.
+// If the namespace is empty, cluster-scoped objects and namespaced objects of
+// all namespaces are listed. Resources, which can not be listed, are skipped.
+func (c *Client) listObjects(ctx context.Context, namespace string) ([]*unstructured.Unstructured, error) {
+	resources, err := c.listableResources(namespace != "")
+	if err != nil {
+		return nil, err
+	}
+	objs := []*unstructured.Unstructured{}
+	seen := sets.NewString()
+	for _, resource := range resources {
+		list, err := c.dynamicClient.Resource(resource).Namespace(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			continue // e.g. forbidden or not actually listable
+		}
+		for i := range list.Items {
+			obj := &list.Items[i]
+			if seen.Has(string(obj.GetUID())) {
+				continue // same resource served by several groups
+			}
+			seen.Insert(string(obj.GetUID()))
+			objs = append(objs, obj)
+		}
+	}
+	return objs, nil
+}
+
+// listableResources discovers the preferred version of all resources, which
+// support list. Namespaced owners can only own namespaced objects.
+func (c *Client) listableResources(namespaced bool) ([]schema.GroupVersionResource, error) {
+	resourceLists, err := c.Clientset.Discovery().ServerPreferredResources()
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, err // partial results are fine, e.g. broken metrics API
+	}
+	resources := []schema.GroupVersionResource{}
+	for _, resourceList := range resourceLists {
+		gv, err := schema.ParseGroupVersion(resourceList.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, resource := range resourceList.APIResources {
+			if strings.Contains(resource.Name, "/") || (namespaced && !resource.Namespaced) ||
+				!sets.NewString(resource.Verbs...).Has("list") {
+				continue
+			}
+			resources = append(resources, gv.WithResource(resource.Name))
+		}
+	}
+	return resources, nil
+}
+
+// reduceObjectsByOwner removes all items of the list, which are not owned by
+// the owner.
+func reduceObjectsByOwner(list runtime.Object, ownerUID types.UID) error {
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return err
+	}
+	matches := []runtime.Object{}
+	for _, item := range items {
+		accessor, err := meta.Accessor(item)
+		if err != nil {
+			return err
+		}
+		for _, ref := range accessor.GetOwnerReferences() {
+			if ref.UID == ownerUID {
+				matches = append(matches, item)
+				break
+			}
+		}
+	}
+	return meta.SetList(list, matches)
+}
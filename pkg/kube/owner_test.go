@@ -0,0 +1,101 @@
+/*
+Copyright 2020 Testutil Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+// mustCreateOwnedConfigMap creates a config map owned by the owner.
+func mustCreateOwnedConfigMap(owner *corev1.ConfigMap) *corev1.ConfigMap {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: owner.Namespace,
+			Name:      owner.Name + "-owned",
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "v1",
+				Kind:       "ConfigMap",
+				Name:       owner.Name,
+				UID:        owner.UID,
+			}},
+		},
+	}
+	gomega.Expect(k8sClient.Create(context.Background(), configMap)).To(gomega.Succeed())
+	return configMap
+}
+
+var _ = Describe("ListForOwner", func() {
+	// for successful usage, see `WaitUntilJobActive`-tests
+	It("fails for non-existing object", func() {
+		job := genPiJob()
+		job.Namespace = "doesnotexist"
+		podList := &corev1.PodList{}
+		gomega.Expect(k8sClient.ListForOwner(context.Background(), podList, job)).ToNot(gomega.Succeed())
+	})
+	It("fails for malformed object", func() {
+		job := genPiJob()
+		job.Namespace = "+"
+		podList := &corev1.PodList{}
+		gomega.Expect(k8sClient.ListForOwner(context.Background(), podList, job)).ToNot(gomega.Succeed())
+	})
+	It("works for any typed or unstructured list", func() {
+		owner := mustCreateConfigMap()
+		defer k8sClient.Delete(context.Background(), owner) // nolint:errcheck
+		owned := mustCreateOwnedConfigMap(owner)
+		defer k8sClient.Delete(context.Background(), owned) // nolint:errcheck
+		configMapList := &corev1.ConfigMapList{}
+		gomega.Expect(k8sClient.ListForOwner(context.Background(), configMapList, owner)).To(gomega.Succeed())
+		gomega.Expect(configMapList.Items).To(gomega.HaveLen(1))
+		gomega.Expect(configMapList.Items[0].UID).To(gomega.Equal(owned.UID))
+		list := &unstructured.UnstructuredList{}
+		list.SetAPIVersion("v1")
+		list.SetKind("ConfigMapList")
+		gomega.Expect(k8sClient.ListForOwner(context.Background(), list, owner)).To(gomega.Succeed())
+		gomega.Expect(list.Items).To(gomega.HaveLen(1))
+		gomega.Expect(list.Items[0].GetUID()).To(gomega.Equal(owned.UID))
+	})
+})
+
+var _ = Describe("Descendants", func() {
+	It("returns transitively owned objects in order", func() {
+		deployment := DeploymentWithNamespacedName(nginxRelease.Namespace, nginxRelease.Name+"-nginx")
+		gomega.Expect(k8sClient.Get(context.Background(), NamespacedName(deployment), deployment)).To(gomega.Succeed())
+		pod := mustGetReadyNginxPod(nginxRelease)
+		descendants, err := k8sClient.Descendants(context.Background(), deployment)
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		kinds := []string{}
+		foundPod := false
+		for _, obj := range descendants {
+			kinds = append(kinds, obj.GetKind())
+			foundPod = foundPod || obj.GetUID() == pod.UID
+		}
+		gomega.Expect(kinds).To(gomega.ContainElement("ReplicaSet"))
+		gomega.Expect(foundPod).To(gomega.Equal(true))
+		gomega.Expect(kinds[0]).To(gomega.Equal("ReplicaSet"))
+	})
+	It("fails for owner without uid", func() {
+		_, err := k8sClient.Descendants(context.Background(), genPiJob())
+		gomega.Expect(err).To(gomega.HaveOccurred())
+	})
+})
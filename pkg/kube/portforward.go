@@ -0,0 +1,529 @@
+/*
+Copyright 2020 Testutil Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const PortAny = 0
+
+// ForwardedPort is a pair of a local port and the remote port forwarded to.
+type ForwardedPort struct {
+	Local int
+	// Remote is the requested port, which is the port of the pod or, if
+	// created by PortForwardService, the port of the service.
+	Remote int
+}
+
+// errLostConnection is reported if the port-forward stopped without an error,
+// which is the case if the connection to the pod was lost.
+var errLostConnection = errors.New("lost connection to pod")
+
+type portForwardOptions struct {
+	Ports     []ForwardedPort
+	Reconnect bool
+}
+
+// PortForwardOption interface is implemented by all possible options to
+// create a port-forward.
+type PortForwardOption interface {
+	apply(*portForwardOptions)
+}
+
+type portForwardOptionAdapter func(*portForwardOptions)
+
+func (c portForwardOptionAdapter) apply(o *portForwardOptions) {
+	c(o)
+}
+
+// PortForwardWithPort will forward an additional port within the same
+// session. If localPort is PortAny, a free local port is used.
+func PortForwardWithPort(localPort, port int) PortForwardOption {
+	return portForwardOptionAdapter(func(o *portForwardOptions) {
+		o.Ports = append(o.Ports, ForwardedPort{Local: localPort, Remote: port})
+	})
+}
+
+// PortForwardWithReconnect will re-establish the port-forward to a replacement
+// pod once the connection to the pod was lost, e.g. because the pod was
+// restarted or deleted. The replacement is the first ready pod of the same
+// controller, service or workload. Only if no replacement can be found, the
+// port-forward is done and Err reports the cause.
+func PortForwardWithReconnect() PortForwardOption {
+	return portForwardOptionAdapter(func(o *portForwardOptions) {
+		o.Reconnect = true
+	})
+}
+
+// podResolver returns the pod to reconnect to.
+type podResolver func(ctx context.Context) (*corev1.Pod, error)
+
+// portMapper maps the requested port to the port of the pod.
+type portMapper func(pod *corev1.Pod, port int) (int, error)
+
+func samePort(pod *corev1.Pod, port int) (int, error) {
+	return port, nil
+}
+
+// PortForward is a running port-forward to a pod. Make sure to always call
+// Close once it is not required anymore.
+type PortForward struct {
+	// LocalPort is the local port of the first forwarded port.
+	LocalPort int
+	// Ports contains all forwarded ports with their actual local port.
+	Ports      []ForwardedPort
+	restConfig *rest.Config
+	restClient rest.Interface
+	options    portForwardOptions
+	resolve    podResolver
+	mapPort    portMapper
+	ctx        context.Context
+	cancel     context.CancelFunc
+	stopCh     chan struct{}
+	doneCh     chan struct{}
+	closeOnce  sync.Once
+	out        *syncBuffer
+	errOut     *syncBuffer
+	mu         sync.Mutex
+	pod        *corev1.Pod
+	err        error
+}
+
+// PortForward creates a port-forward from the local port to the port of the
+// pod. If localPort is PortAny, a free local port is chosen while listening,
+// so it can not be taken by anyone else in the meantime. Additional ports can
+// be forwarded using PortForwardWithPort.
+func (c *Client) PortForward(pod *corev1.Pod, localPort, podPort int, opts ...PortForwardOption) (*PortForward, error) {
+	return c.portForward(pod, localPort, podPort, c.replacementPodResolver(pod), samePort, opts...)
+}
+
+func (c *Client) portForward(pod *corev1.Pod, localPort, port int, resolve podResolver, mapPort portMapper, opts ...PortForwardOption) (*PortForward, error) {
+	options := portForwardOptions{}
+	for _, opt := range opts {
+		opt.apply(&options)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	pf := &PortForward{
+		Ports:      append([]ForwardedPort{{Local: localPort, Remote: port}}, options.Ports...),
+		restConfig: c.restConfig,
+		restClient: c.Clientset.CoreV1().RESTClient(),
+		options:    options,
+		resolve:    resolve,
+		mapPort:    mapPort,
+		ctx:        ctx,
+		cancel:     cancel,
+		stopCh:     make(chan struct{}),
+		doneCh:     make(chan struct{}),
+		out:        &syncBuffer{},
+		errOut:     &syncBuffer{},
+		pod:        pod,
+	}
+	fw, readyCh, errCh := pf.forward(pod)
+	select {
+	case <-readyCh:
+		forwardedPorts, err := fw.GetPorts()
+		if err != nil {
+			pf.stop()
+			return nil, err
+		}
+		for i := range pf.Ports { // keep the local ports for reconnects
+			pf.Ports[i].Local = int(forwardedPorts[i].Local)
+		}
+		pf.LocalPort = pf.Ports[0].Local
+		go pf.run(errCh)
+		return pf, nil
+	case err := <-errCh:
+		pf.stop()
+		return nil, err
+	case <-time.After(30 * time.Second):
+		pf.stop()
+		return nil, fmt.Errorf("port-forward did not become ready in time")
+	}
+}
+
+// forward starts forwarding to the pod in the background. The returned error
+// channel receives the result once the port-forward stopped.
+func (pf *PortForward) forward(pod *corev1.Pod) (*portforward.PortForwarder, <-chan struct{}, <-chan error) {
+	readyCh := make(chan struct{})
+	errCh := make(chan error, 1)
+	ports := []string{}
+	for _, port := range pf.Ports {
+		podPort, err := pf.mapPort(pod, port.Remote)
+		if err != nil {
+			errCh <- err
+			return nil, readyCh, errCh
+		}
+		ports = append(ports, fmt.Sprintf("%d:%d", port.Local, podPort))
+	}
+	dialer, err := newPortForwardDialer(pf.restConfig, pf.restClient, pod)
+	if err != nil {
+		errCh <- err
+		return nil, readyCh, errCh
+	}
+	fw, err := portforward.New(dialer, ports, pf.stopCh, readyCh, pf.out, pf.errOut)
+	if err != nil {
+		errCh <- err
+		return nil, readyCh, errCh
+	}
+	go func() {
+		errCh <- fw.ForwardPorts()
+	}()
+	return fw, readyCh, errCh
+}
+
+// newPortForwardDialer creates a dialer upgrading a connection to the
+// portforward subresource of the pod. The URL is built by the REST client, so
+// the scheme, any path prefix of the host and the API path are respected.
+func newPortForwardDialer(restConfig *rest.Config, restClient rest.Interface, pod *corev1.Pod) (httpstream.Dialer, error) {
+	transport, upgrader, err := spdy.RoundTripperFor(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	url := restClient.Post().
+		Namespace(pod.Namespace).
+		Resource("pods").
+		Name(pod.Name).
+		SubResource("portforward").
+		URL()
+	return spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, url), nil
+}
+
+// run waits until the port-forward stopped and reconnects if enabled.
+func (pf *PortForward) run(errCh <-chan error) {
+	defer close(pf.doneCh)
+	for {
+		err := <-errCh
+		if pf.stopped() {
+			return
+		}
+		if err == nil {
+			err = errLostConnection
+		}
+		if !pf.options.Reconnect {
+			pf.setErr(err)
+			return
+		}
+		if errCh, err = pf.reconnect(err); err != nil {
+			pf.setErr(err)
+		}
+		if errCh == nil {
+			return
+		}
+	}
+}
+
+// reconnect re-establishes the port-forward to a replacement pod. It retries
+// until the port-forward is ready again or no replacement can be found.
+func (pf *PortForward) reconnect(cause error) (<-chan error, error) {
+	backoff := wait.Backoff{Duration: 100 * time.Millisecond, Factor: 2, Cap: 5 * time.Second, Steps: math.MaxInt32}
+	for {
+		fmt.Fprintf(pf.errOut, "%v, reconnecting\n", cause)
+		pod, err := pf.resolve(pf.ctx)
+		if pf.stopped() {
+			return nil, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("%v: %w", cause, err)
+		}
+		_, readyCh, errCh := pf.forward(pod)
+		select {
+		case <-readyCh:
+			pf.mu.Lock()
+			pf.pod = pod
+			pf.mu.Unlock()
+			return errCh, nil
+		case err := <-errCh:
+			if pf.stopped() {
+				return nil, nil
+			}
+			if err != nil {
+				cause = err
+			}
+		}
+		timer := time.NewTimer(backoff.Step())
+		select {
+		case <-pf.ctx.Done():
+			timer.Stop()
+			return nil, nil
+		case <-timer.C:
+		}
+	}
+}
+
+// Pod returns the pod the port-forward is currently connected to.
+func (pf *PortForward) Pod() *corev1.Pod {
+	pf.mu.Lock()
+	defer pf.mu.Unlock()
+	return pf.pod
+}
+
+// Done returns a channel, which is closed once the port-forward stopped,
+// either because Close was called or the connection was lost for good.
+func (pf *PortForward) Done() <-chan struct{} {
+	return pf.doneCh
+}
+
+// Err returns the reason the port-forward stopped. It is nil while the
+// port-forward is running or if it was closed.
+func (pf *PortForward) Err() error {
+	pf.mu.Lock()
+	defer pf.mu.Unlock()
+	return pf.err
+}
+
+// Stdout returns the output of the port-forward so far, e.g. the forwarded
+// ports and handled connections.
+func (pf *PortForward) Stdout() string {
+	return pf.out.String()
+}
+
+// Stderr returns the error output of the port-forward so far, e.g. failed
+// connections and reconnect attempts.
+func (pf *PortForward) Stderr() string {
+	return pf.errOut.String()
+}
+
+func (pf *PortForward) setErr(err error) {
+	pf.mu.Lock()
+	defer pf.mu.Unlock()
+	pf.err = err
+}
+
+func (pf *PortForward) stop() {
+	pf.closeOnce.Do(func() {
+		pf.cancel()
+		close(pf.stopCh)
+	})
+}
+
+func (pf *PortForward) stopped() bool {
+	select {
+	case <-pf.stopCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close stops the port-forward and waits until it is done.
+func (pf *PortForward) Close() error {
+	pf.stop()
+	<-pf.doneCh
+	return nil
+}
+
+// PortForwardService creates a port-forward to a ready pod backing the
+// service similar to `kubectl port-forward svc/name`. The port refers to the
+// port of the service and is mapped to the target port of the pod, which
+// can also be a named container port. If no pod is ready yet, it waits until
+// one becomes ready or the context is done.
+func (c *Client) PortForwardService(ctx context.Context, svc *corev1.Service, localPort, port int, opts ...PortForwardOption) (*PortForward, error) {
+	if len(svc.Spec.Selector) == 0 {
+		return nil, fmt.Errorf("service %s/%s has no selector", svc.Namespace, svc.Name)
+	}
+	if _, err := getServicePort(svc, port); err != nil {
+		return nil, err
+	}
+	resolve := c.selectorPodResolver(svc.Namespace, labels.SelectorFromSet(svc.Spec.Selector))
+	pod, err := resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	mapPort := func(pod *corev1.Pod, port int) (int, error) {
+		servicePort, err := getServicePort(svc, port)
+		if err != nil {
+			return 0, err
+		}
+		return getTargetPort(servicePort, pod)
+	}
+	return c.portForward(pod, localPort, port, resolve, mapPort, opts...)
+}
+
+// PortForwardWorkload creates a port-forward to a ready pod of the workload,
+// e.g. a Deployment, StatefulSet or DaemonSet. The pods are resolved using
+// the `spec.selector` of the workload, so custom resources following the
+// same convention are supported as well. If no pod is ready yet, it waits
+// until one becomes ready or the context is done.
+func (c *Client) PortForwardWorkload(ctx context.Context, workload runtime.Object, localPort, podPort int, opts ...PortForwardOption) (*PortForward, error) {
+	selector, err := getWorkloadSelector(workload)
+	if err != nil {
+		return nil, err
+	}
+	namespace := NamespacedName(workload).Namespace
+	pod, err := c.getReadyPod(ctx, namespace, selector)
+	if err != nil {
+		return nil, err
+	}
+	return c.portForward(pod, localPort, podPort, c.selectorPodResolver(namespace, selector), samePort, opts...)
+}
+
+func (c *Client) selectorPodResolver(namespace string, selector labels.Selector) podResolver {
+	return func(ctx context.Context) (*corev1.Pod, error) {
+		return c.getReadyPod(ctx, namespace, selector)
+	}
+}
+
+// replacementPodResolver resolves a replacement for the pod using the selector
+// of its controller. Pods without controller can only be replaced by a ready
+// pod with the same name.
+func (c *Client) replacementPodResolver(pod *corev1.Pod) podResolver {
+	return func(ctx context.Context) (*corev1.Pod, error) {
+		selector, err := c.getControllerSelector(ctx, pod)
+		if err != nil {
+			return nil, err
+		}
+		if selector == nil {
+			return c.getReadyPod(ctx, pod.Namespace, labels.Everything(),
+				client.MatchingFields{"metadata.name": pod.Name})
+		}
+		return c.getReadyPod(ctx, pod.Namespace, selector)
+	}
+}
+
+// getControllerSelector retrieves the `spec.selector` of the controller of the
+// pod. If the pod is not controlled, the returned selector is nil.
+func (c *Client) getControllerSelector(ctx context.Context, pod *corev1.Pod) (labels.Selector, error) {
+	ref := metav1.GetControllerOf(pod)
+	if ref == nil {
+		return nil, nil
+	}
+	owner := &unstructured.Unstructured{}
+	owner.SetAPIVersion(ref.APIVersion)
+	owner.SetKind(ref.Kind)
+	if err := c.Get(ctx, types.NamespacedName{Namespace: pod.Namespace, Name: ref.Name}, owner); err != nil {
+		return nil, err
+	}
+	return getWorkloadSelector(owner)
+}
+
+// getReadyPod returns the first ready pod matching the selector. If no pod is
+// ready, the pods are polled until one becomes ready or the context is done.
+func (c *Client) getReadyPod(ctx context.Context, namespace string, selector labels.Selector, opts ...client.ListOption) (*corev1.Pod, error) {
+	backoff := c.pollBackoff()
+	opts = append([]client.ListOption{client.InNamespace(namespace),
+		client.MatchingLabelsSelector{Selector: selector}}, opts...)
+	for {
+		podList := &corev1.PodList{}
+		err := c.List(ctx, podList, opts...)
+		if err != nil {
+			return nil, err
+		}
+		for i := range podList.Items {
+			pod := &podList.Items[i]
+			if pod.DeletionTimestamp == nil && IsPodReady(pod) {
+				return pod, nil
+			}
+		}
+		timer := time.NewTimer(backoff.Step())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("no ready pod found for selector %q: %w", selector, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+func getServicePort(svc *corev1.Service, port int) (*corev1.ServicePort, error) {
+	for i := range svc.Spec.Ports {
+		if int(svc.Spec.Ports[i].Port) == port {
+			return &svc.Spec.Ports[i], nil
+		}
+	}
+	return nil, fmt.Errorf("service %s/%s does not have port %d", svc.Namespace, svc.Name, port)
+}
+
+// getTargetPort resolves the target port of the service port for the pod.
+// Named target ports are looked up in the container ports of the pod.
+func getTargetPort(servicePort *corev1.ServicePort, pod *corev1.Pod) (int, error) {
+	targetPort := servicePort.TargetPort
+	if targetPort.Type == intstr.Int {
+		if targetPort.IntVal == 0 { // defaults to the port of the service
+			return int(servicePort.Port), nil
+		}
+		return int(targetPort.IntVal), nil
+	}
+	for _, container := range pod.Spec.Containers {
+		for _, port := range container.Ports {
+			if port.Name == targetPort.StrVal {
+				return int(port.ContainerPort), nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("pod %s/%s does not have named port %s", pod.Namespace, pod.Name, targetPort.StrVal)
+}
+
+// getWorkloadSelector retrieves the `spec.selector` of the workload.
+func getWorkloadSelector(workload runtime.Object) (labels.Selector, error) {
+	content, err := toUnstructuredContent(workload)
+	if err != nil {
+		return nil, err
+	}
+	spec, ok := content["spec"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("workload of type %T has no spec", workload)
+	}
+	rawSelector, ok := spec["selector"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("workload of type %T has no selector", workload)
+	}
+	labelSelector := &metav1.LabelSelector{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(rawSelector, labelSelector); err != nil {
+		return nil, err
+	}
+	return metav1.LabelSelectorAsSelector(labelSelector)
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent use, as the port-forward
+// writes its output in the background.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
@@ -0,0 +1,241 @@
+/*
+Copyright 2020 Testutil Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+// fakeAPIServer records the requests and denies all of them.
+type fakeAPIServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []string
+}
+
+func newFakeAPIServer(tls bool) *fakeAPIServer {
+	s := &fakeAPIServer{}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusForbidden)
+	})
+	if tls {
+		s.Server = httptest.NewTLSServer(handler)
+	} else {
+		s.Server = httptest.NewServer(handler)
+	}
+	return s
+}
+
+func (s *fakeAPIServer) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.requests...)
+}
+
+func mustCreateFakeClient(restConfig *rest.Config) *Client {
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	gomega.Expect(err).ToNot(gomega.HaveOccurred())
+	return &Client{Clientset: clientset, restConfig: restConfig}
+}
+
+var _ = Describe("PortForward URL", func() {
+	var pod *corev1.Pod
+
+	BeforeEach(func() {
+		pod = &corev1.Pod{}
+		pod.Namespace = "default"
+		pod.Name = "foo"
+	})
+	It("supports http API servers", func() {
+		server := newFakeAPIServer(false)
+		defer server.Close()
+		c := mustCreateFakeClient(&rest.Config{Host: server.URL})
+		_, err := c.PortForward(pod, PortAny, 8080)
+		gomega.Expect(err).To(gomega.HaveOccurred())
+		_, err = c.NewPodDialer(pod)
+		gomega.Expect(err).To(gomega.HaveOccurred())
+		gomega.Expect(server.Requests()).To(gomega.Equal([]string{
+			"POST /api/v1/namespaces/default/pods/foo/portforward",
+			"POST /api/v1/namespaces/default/pods/foo/portforward",
+		}))
+	})
+	It("supports API servers with path prefix", func() {
+		server := newFakeAPIServer(false)
+		defer server.Close()
+		c := mustCreateFakeClient(&rest.Config{Host: server.URL + "/prefix/"})
+		_, err := c.PortForward(pod, PortAny, 8080)
+		gomega.Expect(err).To(gomega.HaveOccurred())
+		gomega.Expect(server.Requests()).To(gomega.Equal([]string{
+			"POST /prefix/api/v1/namespaces/default/pods/foo/portforward",
+		}))
+	})
+	It("uses https for hosts without scheme if TLS is configured", func() {
+		server := newFakeAPIServer(true)
+		defer server.Close()
+		c := mustCreateFakeClient(&rest.Config{
+			Host:            strings.TrimPrefix(server.URL, "https://"),
+			TLSClientConfig: rest.TLSClientConfig{Insecure: true},
+		})
+		_, err := c.PortForward(pod, PortAny, 8080)
+		gomega.Expect(err).To(gomega.HaveOccurred())
+		gomega.Expect(server.Requests()).To(gomega.Equal([]string{
+			"POST /api/v1/namespaces/default/pods/foo/portforward",
+		}))
+	})
+})
+
+var _ = Describe("PortForward", func() {
+	It("can portforward existing pod", func() {
+		rls := mustInstallNginx()
+		defer helmClient.Uninstall(rls.Name) // nolint:errcheck
+		pod := mustGetReadyNginxPod(rls)
+		gomega.Expect(func() {
+			gomega.Expect(k8sClient.Get(context.Background(), NamespacedName(pod), pod)).To(gomega.Succeed())
+		}).ShouldNot(gomega.Panic())
+		By("creating port-forward")
+		pf, err := k8sClient.PortForward(pod, PortAny, 8080)
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		defer pf.Close()
+		gomega.Expect(checkNginxServer(fmt.Sprintf("http://localhost:%d", pf.LocalPort))).To(gomega.Succeed())
+	})
+	It("can portforward multiple ports in one session", func() {
+		pod := mustGetReadyNginxPod(nginxRelease)
+		pf, err := k8sClient.PortForward(pod, PortAny, 8080, PortForwardWithPort(PortAny, 8080))
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		defer pf.Close()
+		gomega.Expect(pf.Ports).To(gomega.HaveLen(2))
+		gomega.Expect(pf.Ports[0].Local).To(gomega.Equal(pf.LocalPort))
+		gomega.Expect(pf.Ports[1].Local).ToNot(gomega.Equal(pf.LocalPort))
+		for _, port := range pf.Ports {
+			gomega.Expect(port.Remote).To(gomega.Equal(8080))
+			gomega.Expect(checkNginxServer(fmt.Sprintf("http://localhost:%d", port.Local))).To(gomega.Succeed())
+		}
+	})
+	It("reports lost connection once pod is deleted", func() {
+		rls := mustInstallNginx()
+		defer helmClient.Uninstall(rls.Name) // nolint:errcheck
+		pod := mustGetReadyNginxPod(rls)
+		pf, err := k8sClient.PortForward(pod, PortAny, 8080)
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		defer pf.Close()
+		gomega.Expect(checkNginxServer(fmt.Sprintf("http://localhost:%d", pf.LocalPort))).To(gomega.Succeed())
+		gomega.Expect(pf.Stdout()).To(gomega.ContainSubstring("Forwarding from"))
+		gomega.Expect(pf.Err()).ToNot(gomega.HaveOccurred())
+		gomega.Expect(k8sClient.Delete(context.Background(), pod)).To(gomega.Succeed())
+		gomega.Eventually(pf.Done(), timeout).Should(gomega.BeClosed())
+		gomega.Expect(pf.Err()).To(gomega.MatchError(errLostConnection))
+	})
+	It("reconnects to replacement pod", func() {
+		rls := mustInstallNginx()
+		defer helmClient.Uninstall(rls.Name) // nolint:errcheck
+		pod := mustGetReadyNginxPod(rls)
+		pf, err := k8sClient.PortForward(pod, PortAny, 8080, PortForwardWithReconnect())
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		defer pf.Close()
+		url := fmt.Sprintf("http://localhost:%d", pf.LocalPort)
+		gomega.Expect(checkNginxServer(url)).To(gomega.Succeed())
+		gomega.Expect(k8sClient.Delete(context.Background(), pod)).To(gomega.Succeed())
+		gomega.Eventually(func() types.UID {
+			return pf.Pod().UID
+		}, timeout).ShouldNot(gomega.Equal(pod.UID))
+		gomega.Eventually(func() error {
+			return checkNginxServer(url)
+		}, timeout).Should(gomega.Succeed())
+		gomega.Expect(pf.Done()).ToNot(gomega.BeClosed())
+		gomega.Expect(pf.Close()).To(gomega.Succeed())
+		gomega.Expect(pf.Done()).To(gomega.BeClosed())
+		gomega.Expect(pf.Err()).ToNot(gomega.HaveOccurred())
+	})
+	It("fails with invalid host port", func() {
+		rls := mustInstallNginx()
+		defer helmClient.Uninstall(rls.Name) // nolint:errcheck
+		pod := mustGetReadyNginxPod(rls)
+		pf, err := k8sClient.PortForward(pod, 999999, 8080)
+		gomega.Expect(err).To(gomega.HaveOccurred())
+		gomega.Expect(pf).To(gomega.BeNil())
+	})
+	It("fails for non-existing pod", func() {
+		var pod corev1.Pod
+		pod.ObjectMeta.Namespace = "default"
+		pod.ObjectMeta.Name = "doesnotexist"
+		pf, err := k8sClient.PortForward(&pod, PortAny, 8080)
+		gomega.Expect(err).To(gomega.HaveOccurred())
+		gomega.Expect(pf).To(gomega.BeNil())
+	})
+	It("can portforward service with named target port", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		svc := &corev1.Service{}
+		gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{
+			Namespace: nginxRelease.Namespace,
+			Name:      nginxRelease.Name + "-nginx",
+		}, svc)).To(gomega.Succeed())
+		pf, err := k8sClient.PortForwardService(ctx, svc, PortAny, 80)
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		defer pf.Close()
+		gomega.Expect(checkNginxServer(fmt.Sprintf("http://localhost:%d", pf.LocalPort))).To(gomega.Succeed())
+	})
+	It("fails for unknown service port", func() {
+		svc := &corev1.Service{}
+		gomega.Expect(k8sClient.Get(context.Background(), types.NamespacedName{
+			Namespace: nginxRelease.Namespace,
+			Name:      nginxRelease.Name + "-nginx",
+		}, svc)).To(gomega.Succeed())
+		pf, err := k8sClient.PortForwardService(context.Background(), svc, PortAny, 12345)
+		gomega.Expect(err).To(gomega.HaveOccurred())
+		gomega.Expect(pf).To(gomega.BeNil())
+	})
+	It("can portforward deployment", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		deployment := DeploymentWithNamespacedName(nginxRelease.Namespace, nginxRelease.Name+"-nginx")
+		gomega.Expect(k8sClient.Get(ctx, NamespacedName(deployment), deployment)).To(gomega.Succeed())
+		pf, err := k8sClient.PortForwardWorkload(ctx, deployment, PortAny, 8080)
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		defer pf.Close()
+		gomega.Expect(checkNginxServer(fmt.Sprintf("http://localhost:%d", pf.LocalPort))).To(gomega.Succeed())
+	})
+	It("fails if no pod becomes ready", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		deployment := DeploymentWithNamespacedName("default", "doesnotexist")
+		deployment.Spec.Selector = &metav1.LabelSelector{
+			MatchLabels: map[string]string{"app": "doesnotexist"},
+		}
+		pf, err := k8sClient.PortForwardWorkload(ctx, deployment, PortAny, 8080)
+		gomega.Expect(err).To(gomega.HaveOccurred())
+		gomega.Expect(pf).To(gomega.BeNil())
+	})
+})
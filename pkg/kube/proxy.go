@@ -0,0 +1,53 @@
+/*
+Copyright 2020 Testutil Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"fmt"
+	"net/http"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/rest"
+)
+
+// ServiceProxyClient returns a http.Client and the base URL to reach the port
+// of the service through the proxy of the API server, so neither local ports
+// nor port-forwards are required. Paths are simply appended to the base URL,
+// e.g. `baseURL + "/healthz"`. The port refers to the port of the service.
+func (c *Client) ServiceProxyClient(svc *corev1.Service, port int) (*http.Client, string, error) {
+	return c.proxyClient("services", svc.Namespace, svc.Name, port)
+}
+
+// PodProxyClient returns a http.Client and the base URL to reach the port of
+// the pod through the proxy of the API server similar to ServiceProxyClient.
+func (c *Client) PodProxyClient(pod *corev1.Pod, port int) (*http.Client, string, error) {
+	return c.proxyClient("pods", pod.Namespace, pod.Name, port)
+}
+
+func (c *Client) proxyClient(resource, namespace, name string, port int) (*http.Client, string, error) {
+	transport, err := rest.TransportFor(c.restConfig)
+	if err != nil {
+		return nil, "", err
+	}
+	url := c.Clientset.CoreV1().RESTClient().Get().
+		Namespace(namespace).
+		Resource(resource).
+		Name(fmt.Sprintf("%s:%d", name, port)).
+		SubResource("proxy").
+		URL()
+	return &http.Client{Transport: transport}, url.String(), nil
+}
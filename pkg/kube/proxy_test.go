@@ -0,0 +1,56 @@
+/*
+Copyright 2020 Testutil Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"context"
+	"net/http"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+func mustGetThroughProxy(httpClient *http.Client, url string) {
+	resp, err := httpClient.Get(url)
+	gomega.Expect(err).ToNot(gomega.HaveOccurred())
+	defer resp.Body.Close()
+	gomega.Expect(resp.StatusCode).To(gomega.Equal(http.StatusOK))
+}
+
+var _ = Describe("ServiceProxyClient", func() {
+	It("can reach service through proxy", func() {
+		svc := &corev1.Service{}
+		gomega.Expect(k8sClient.Get(context.Background(), types.NamespacedName{
+			Namespace: nginxRelease.Namespace,
+			Name:      nginxRelease.Name + "-nginx",
+		}, svc)).To(gomega.Succeed())
+		httpClient, baseURL, err := k8sClient.ServiceProxyClient(svc, 80)
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		gomega.Expect(baseURL).To(gomega.HaveSuffix("/api/v1/namespaces/" + svc.Namespace +
+			"/services/" + svc.Name + ":80/proxy"))
+		mustGetThroughProxy(httpClient, baseURL+"/")
+	})
+	It("can reach pod through proxy", func() {
+		pod := mustGetReadyNginxPod(nginxRelease)
+		httpClient, baseURL, err := k8sClient.PodProxyClient(pod, 8080)
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		mustGetThroughProxy(httpClient, baseURL+"/")
+	})
+})
@@ -0,0 +1,226 @@
+/*
+Copyright 2020 Testutil Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"errors"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// ReadyStatus is the overall status of an object computed by IsReady.
+type ReadyStatus string
+
+const (
+	// InProgressStatus indicates the object is still being reconciled.
+	InProgressStatus ReadyStatus = "InProgress"
+	// CurrentStatus indicates the object is fully reconciled and ready.
+	CurrentStatus ReadyStatus = "Current"
+	// FailedStatus indicates the object failed and will not become ready.
+	FailedStatus ReadyStatus = "Failed"
+	// TerminatingStatus indicates the object is being deleted.
+	TerminatingStatus ReadyStatus = "Terminating"
+)
+
+// ReadyResult is the result of IsReady.
+type ReadyResult struct {
+	Status ReadyStatus
+	// Message is a human-readable explanation of the status.
+	Message string
+}
+
+// IsReady computes the status of any object similar to kstatus. Built-in
+// workloads are checked using their dedicated helpers, e.g.
+// IsDeploymentRolloutComplete. All other objects, including
+// unstructured.Unstructured, are checked by their deletion timestamp,
+// `status.observedGeneration` and `status.conditions`.
+//
+// Unstructured objects are converted using the default client-go scheme. Use
+// Client.IsReady to take the scheme of the client into account.
+func IsReady(obj runtime.Object) (ReadyResult, error) {
+	return isReady(scheme.Scheme, obj)
+}
+
+// IsReady computes the status of the object like the package-level IsReady,
+// but converts unstructured objects using the scheme of the client.
+func (c *Client) IsReady(obj runtime.Object) (ReadyResult, error) {
+	return isReady(c.scheme, obj)
+}
+
+func isReady(s *runtime.Scheme, obj runtime.Object) (ReadyResult, error) {
+	content, err := toUnstructuredContent(obj)
+	if err != nil {
+		return ReadyResult{}, err
+	}
+	u := &unstructured.Unstructured{Object: content}
+	if u.GetDeletionTimestamp() != nil {
+		return ReadyResult{TerminatingStatus, "object is being deleted"}, nil
+	}
+	observedGeneration, found, err := unstructured.NestedInt64(content, "status", "observedGeneration")
+	if err == nil && found && observedGeneration < u.GetGeneration() {
+		return ReadyResult{InProgressStatus, fmt.Sprintf("observed generation %d, expected %d",
+			observedGeneration, u.GetGeneration())}, nil
+	}
+	typed, err := toTypedObject(s, obj)
+	if err != nil {
+		return ReadyResult{}, err
+	}
+	switch o := typed.(type) {
+	case *corev1.Pod:
+		return podReadyResult(o), nil
+	case *appsv1.Deployment:
+		if err := DeploymentRolloutError(o); err != nil {
+			return ReadyResult{FailedStatus, err.Error()}, nil
+		}
+		return readyResultFor(IsDeploymentRolloutComplete(o), "rollout not complete"), nil
+	case *appsv1.ReplicaSet:
+		return readyResultFor(IsReplicaSetAvailable(o), "not all replicas available"), nil
+	case *appsv1.StatefulSet:
+		return readyResultFor(IsStatefulSetReady(o), "not all replicas ready and updated"), nil
+	case *appsv1.DaemonSet:
+		return readyResultFor(IsDaemonSetReady(o), "not all pods ready and updated"), nil
+	case *batchv1.Job:
+		if err := JobTerminalError(o); err != nil {
+			return ReadyResult{FailedStatus, err.Error()}, nil
+		}
+		return readyResultFor(IsJobComplete(o), "job not complete"), nil
+	}
+	return conditionsReadyResult(u), nil
+}
+
+func readyResultFor(ready bool, message string) ReadyResult {
+	if ready {
+		return ReadyResult{Status: CurrentStatus}
+	}
+	return ReadyResult{InProgressStatus, message}
+}
+
+func podReadyResult(pod *corev1.Pod) ReadyResult {
+	if err := PodTerminalError(pod); err != nil {
+		return ReadyResult{FailedStatus, err.Error()}
+	}
+	if pod.Status.Phase == corev1.PodSucceeded {
+		return ReadyResult{Status: CurrentStatus}
+	}
+	return readyResultFor(IsPodReady(pod), "not all containers ready")
+}
+
+// conditionsReadyResult computes the status using well-known types of
+// `status.conditions`. Objects without any of those are considered current.
+func conditionsReadyResult(u *unstructured.Unstructured) ReadyResult {
+	conditions, _, _ := unstructured.NestedSlice(u.Object, "status", "conditions")
+	statuses := map[string]map[string]interface{}{}
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		conditionType, _, _ := unstructured.NestedString(condition, "type")
+		statuses[conditionType] = condition
+	}
+	isTrue := func(conditionType string) (bool, string, bool) {
+		condition, ok := statuses[conditionType]
+		if !ok {
+			return false, "", false
+		}
+		status, _, _ := unstructured.NestedString(condition, "status")
+		message, _, _ := unstructured.NestedString(condition, "message")
+		return status == string(corev1.ConditionTrue), message, true
+	}
+	for _, conditionType := range []string{"Stalled", "Failed"} {
+		if ok, message, _ := isTrue(conditionType); ok {
+			return ReadyResult{FailedStatus, message}
+		}
+	}
+	if ok, message, _ := isTrue("Reconciling"); ok {
+		return ReadyResult{InProgressStatus, message}
+	}
+	for _, conditionType := range []string{"Ready", "Available"} {
+		if ok, message, found := isTrue(conditionType); found {
+			if ok {
+				return ReadyResult{Status: CurrentStatus}
+			}
+			return ReadyResult{InProgressStatus, message}
+		}
+	}
+	return ReadyResult{Status: CurrentStatus}
+}
+
+func toUnstructuredContent(obj runtime.Object) (map[string]interface{}, error) {
+	if obj == nil {
+		return nil, errors.New("object can not be nil")
+	}
+	if u, ok := obj.(runtime.Unstructured); ok {
+		return u.UnstructuredContent(), nil
+	}
+	return runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+}
+
+// toTypedObject converts unstructured objects of kinds known to the scheme to
+// their typed counterpart. All other objects are returned as is.
+func toTypedObject(s *runtime.Scheme, obj runtime.Object) (runtime.Object, error) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return obj, nil
+	}
+	typed, err := s.New(u.GroupVersionKind())
+	if err != nil {
+		return obj, nil // unknown kind, so stick with unstructured
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, typed); err != nil {
+		return nil, err
+	}
+	return typed, nil
+}
+
+// ObjectIsReady waits until IsReady reports the object as current. If the
+// object failed, WaitUntil returns a *TerminalError. Errors computing the
+// status are returned as is.
+func (c *Client) ObjectIsReady(obj runtime.Object) Condition {
+	var (
+		result ReadyResult
+		err    error
+	)
+	return TerminalConditionFunc(obj, "ObjectIsReady", func() bool {
+		result, err = c.IsReady(obj)
+		return err == nil && result.Status == CurrentStatus
+	}, func() error {
+		if err != nil {
+			return err
+		}
+		if result.Status != FailedStatus {
+			return nil
+		}
+		kind := ""
+		if gvk, err := apiutil.GVKForObject(obj, c.scheme); err == nil {
+			kind = gvk.Kind
+		}
+		return &TerminalError{
+			Kind:    kind,
+			Object:  NamespacedName(obj),
+			Reason:  string(FailedStatus),
+			Message: result.Message,
+		}
+	})
+}
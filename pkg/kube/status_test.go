@@ -0,0 +1,98 @@
+/*
+Copyright 2020 Testutil Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+func genCustomResource(conditions ...interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "Foo",
+		"metadata": map[string]interface{}{
+			"namespace":  "default",
+			"name":       "foo",
+			"generation": int64(2),
+		},
+		"status": map[string]interface{}{
+			"observedGeneration": int64(2),
+			"conditions":         conditions,
+		},
+	}}
+}
+
+func genCondition(conditionType, status string) interface{} {
+	return map[string]interface{}{
+		"type":    conditionType,
+		"status":  status,
+		"message": conditionType + " is " + status,
+	}
+}
+
+func mustBeReadyWith(obj *unstructured.Unstructured, status ReadyStatus) {
+	result, err := IsReady(obj)
+	gomega.Expect(err).ToNot(gomega.HaveOccurred())
+	gomega.Expect(result.Status).To(gomega.Equal(status))
+}
+
+var _ = Describe("IsReady", func() {
+	It("uses well-known status conditions", func() {
+		mustBeReadyWith(genCustomResource(), CurrentStatus)
+		mustBeReadyWith(genCustomResource(genCondition("Ready", "True")), CurrentStatus)
+		mustBeReadyWith(genCustomResource(genCondition("Ready", "False")), InProgressStatus)
+		mustBeReadyWith(genCustomResource(genCondition("Available", "False")), InProgressStatus)
+		mustBeReadyWith(genCustomResource(genCondition("Reconciling", "True")), InProgressStatus)
+		mustBeReadyWith(genCustomResource(genCondition("Stalled", "True")), FailedStatus)
+	})
+	It("respects observed generation", func() {
+		obj := genCustomResource(genCondition("Ready", "True"))
+		obj.SetGeneration(3)
+		mustBeReadyWith(obj, InProgressStatus)
+	})
+	It("detects terminating objects", func() {
+		obj := genCustomResource(genCondition("Ready", "True"))
+		now := metav1.Now()
+		obj.SetDeletionTimestamp(&now)
+		mustBeReadyWith(obj, TerminatingStatus)
+	})
+	It("fails for nil object", func() {
+		_, err := IsReady(nil)
+		gomega.Expect(err).To(gomega.HaveOccurred())
+	})
+	It("can be used with WaitUntil for typed and unstructured objects", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		deployment := DeploymentWithNamespacedName(nginxRelease.Namespace, nginxRelease.Name+"-nginx")
+		gomega.Expect(k8sClient.WaitUntil(ctx, k8sClient.ObjectIsReady(deployment))).To(gomega.Succeed())
+		u := &unstructured.Unstructured{}
+		u.SetAPIVersion("apps/v1")
+		u.SetKind("Deployment")
+		u.SetNamespace(deployment.Namespace)
+		u.SetName(deployment.Name)
+		gomega.Expect(k8sClient.WaitUntil(ctx, k8sClient.ObjectIsReady(u))).To(gomega.Succeed())
+		result, err := k8sClient.IsReady(u)
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		gomega.Expect(result.Status).To(gomega.Equal(CurrentStatus))
+	})
+})
@@ -28,6 +28,7 @@ import (
 	"github.com/kubism/testutil/pkg/kind"
 	"github.com/kubism/testutil/pkg/rand"
 
+	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
 	batchv1beta1 "k8s.io/api/batch/v1beta1"
 	corev1 "k8s.io/api/core/v1"
@@ -36,7 +37,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	. "github.com/onsi/ginkgo"
-	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega"
 )
 
 const (
@@ -52,7 +53,7 @@ var (
 )
 
 func TestHelm(t *testing.T) {
-	RegisterFailHandler(Fail)
+	gomega.RegisterFailHandler(Fail)
 	RunSpecs(t, "kube")
 }
 
@@ -70,24 +71,24 @@ var _ = BeforeSuite(func(done Done) {
 		)
 	}
 	cluster, err = kind.NewCluster(clusterOptions...)
-	Expect(err).To(Succeed())
+	gomega.Expect(err).To(gomega.Succeed())
 	restConfig, err = cluster.GetRESTConfig()
-	Expect(err).To(Succeed())
-	Expect(restConfig).ToNot(BeNil())
+	gomega.Expect(err).To(gomega.Succeed())
+	gomega.Expect(restConfig).ToNot(gomega.BeNil())
 	By("setup helm client")
 	kubeConfig, err := cluster.GetKubeConfig()
-	Expect(err).To(Succeed())
+	gomega.Expect(err).To(gomega.Succeed())
 	helmClient, err = helm.NewClient(kubeConfig)
-	Expect(err).To(Succeed())
-	Expect(helmClient).ToNot(BeNil())
-	Expect(helmClient.AddRepository(&helm.RepositoryEntry{
+	gomega.Expect(err).To(gomega.Succeed())
+	gomega.Expect(helmClient).ToNot(gomega.BeNil())
+	gomega.Expect(helmClient.AddRepository(&helm.RepositoryEntry{
 		Name: "bitnami",
 		URL:  "https://charts.bitnami.com/bitnami",
-	})).To(Succeed())
+	})).To(gomega.Succeed())
 	By("setup k8s client")
 	k8sClient, err = NewClient(restConfig)
-	Expect(err).To(Succeed())
-	Expect(k8sClient).ToNot(BeNil())
+	gomega.Expect(err).To(gomega.Succeed())
+	gomega.Expect(k8sClient).ToNot(gomega.BeNil())
 	By("setup prepared nginx release")
 	nginxRelease = mustInstallNginx()
 	close(done)
@@ -111,8 +112,8 @@ var _ = AfterSuite(func() {
 func mustInstallNginx() *helm.Release {
 	By("helm install bitnami/nginx")
 	rls, err := helmClient.Install("bitnami/nginx", "", helm.ValuesOptions{})
-	Expect(err).ToNot(HaveOccurred())
-	Expect(rls).ToNot(BeNil())
+	gomega.Expect(err).ToNot(gomega.HaveOccurred())
+	gomega.Expect(rls).ToNot(gomega.BeNil())
 	By(fmt.Sprintf("helm installed %s", rls.Name))
 	return rls
 }
@@ -122,15 +123,15 @@ func mustGetReadyNginxPod(rls *helm.Release) *corev1.Pod {
 	defer cancel()
 	pods := &corev1.PodList{}
 	deployment := DeploymentWithNamespacedName(rls.Namespace, rls.Name+"-nginx")
-	Expect(k8sClient.Get(ctx, NamespacedName(deployment), deployment)).To(Succeed())
+	gomega.Expect(k8sClient.Get(ctx, NamespacedName(deployment), deployment)).To(gomega.Succeed())
 	By(fmt.Sprintf("waiting until deployment %s-nginx is scheduled", rls.Name))
-	Expect(k8sClient.WaitUntil(ctx, DeploymentIsScheduled(deployment))).To(Succeed())
-	Expect(k8sClient.List(ctx, pods, client.InNamespace(rls.Namespace),
-		client.MatchingLabels{"app.kubernetes.io/instance": rls.Name})).To(Succeed())
-	Expect(len(pods.Items)).To(BeNumerically(">", 0))
+	gomega.Expect(k8sClient.WaitUntil(ctx, DeploymentIsScheduled(deployment))).To(gomega.Succeed())
+	gomega.Expect(k8sClient.List(ctx, pods, client.InNamespace(rls.Namespace),
+		client.MatchingLabels{"app.kubernetes.io/instance": rls.Name})).To(gomega.Succeed())
+	gomega.Expect(len(pods.Items)).To(gomega.BeNumerically(">", 0))
 	pod := pods.Items[0]
 	By("waiting until pod is ready")
-	Expect(k8sClient.WaitUntil(ctx, PodIsReady(&pod))).To(Succeed())
+	gomega.Expect(k8sClient.WaitUntil(ctx, PodIsReady(&pod))).To(gomega.Succeed())
 	return &pod
 }
 
@@ -146,6 +147,65 @@ func checkNginxServer(url string) error {
 	return nil
 }
 
+func genNginxPodTemplate(labels map[string]string) corev1.PodTemplateSpec {
+	return corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: labels,
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:  "nginx",
+					Image: "nginx:alpine",
+				},
+			},
+		},
+	}
+}
+
+func genNginxStatefulSet() *appsv1.StatefulSet {
+	name := "nginx-sts-" + rand.String(5)
+	labels := map[string]string{"app": name}
+	return &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      name,
+		},
+		Spec: appsv1.StatefulSetSpec{
+			ServiceName: name,
+			Selector:    &metav1.LabelSelector{MatchLabels: labels},
+			Template:    genNginxPodTemplate(labels),
+		},
+	}
+}
+
+func mustCreateNginxStatefulSet() *appsv1.StatefulSet {
+	sts := genNginxStatefulSet()
+	gomega.Expect(k8sClient.Create(context.Background(), sts)).To(gomega.Succeed())
+	return sts
+}
+
+func genNginxDaemonSet() *appsv1.DaemonSet {
+	name := "nginx-ds-" + rand.String(5)
+	labels := map[string]string{"app": name}
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      name,
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: genNginxPodTemplate(labels),
+		},
+	}
+}
+
+func mustCreateNginxDaemonSet() *appsv1.DaemonSet {
+	ds := genNginxDaemonSet()
+	gomega.Expect(k8sClient.Create(context.Background(), ds)).To(gomega.Succeed())
+	return ds
+}
+
 func genPiJob() *batchv1.Job {
 	backoffLimit := int32(3)
 	return &batchv1.Job{
@@ -173,7 +233,7 @@ func genPiJob() *batchv1.Job {
 
 func mustCreatePiJob() *batchv1.Job {
 	job := genPiJob()
-	Expect(k8sClient.Create(context.Background(), job)).To(Succeed())
+	gomega.Expect(k8sClient.Create(context.Background(), job)).To(gomega.Succeed())
 	return job
 }
 
@@ -195,6 +255,6 @@ func genPiCronJob() *batchv1beta1.CronJob {
 
 func mustCreatePiCronJob() *batchv1beta1.CronJob {
 	cronJob := genPiCronJob()
-	Expect(k8sClient.Create(context.Background(), cronJob)).To(Succeed())
+	gomega.Expect(k8sClient.Create(context.Background(), cronJob)).To(gomega.Succeed())
 	return cronJob
 }
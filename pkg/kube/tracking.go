@@ -0,0 +1,105 @@
+/*
+Copyright 2020 Testutil Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"context"
+	"sync"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// trackingClient records all objects created through the embedded client,
+// either by Create or a server-side apply Patch.
+type trackingClient struct {
+	client.Client
+	mu      sync.Mutex
+	created []runtime.Object
+}
+
+func (t *trackingClient) Create(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+	if err := t.Client.Create(ctx, obj, opts...); err != nil {
+		return err
+	}
+	createOptions := &client.CreateOptions{}
+	createOptions.ApplyOptions(opts)
+	if len(createOptions.DryRun) > 0 {
+		return nil // nothing was persisted
+	}
+	t.record(obj)
+	return nil
+}
+
+// Patch records objects, which were created by a server-side apply patch.
+func (t *trackingClient) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if patch.Type() != types.ApplyPatchType {
+		return t.Client.Patch(ctx, obj, patch, opts...)
+	}
+	key, err := client.ObjectKeyFromObject(obj)
+	if err != nil {
+		return err
+	}
+	err = t.Client.Get(ctx, key, obj.DeepCopyObject())
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	existed := err == nil
+	if err := t.Client.Patch(ctx, obj, patch, opts...); err != nil {
+		return err
+	}
+	patchOptions := &client.PatchOptions{}
+	patchOptions.ApplyOptions(opts)
+	if existed || len(patchOptions.DryRun) > 0 {
+		return nil
+	}
+	t.record(obj)
+	return nil
+}
+
+func (t *trackingClient) record(obj runtime.Object) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.created = append(t.created, obj.DeepCopyObject())
+}
+
+// reset returns all recorded objects and forgets them.
+func (t *trackingClient) reset() []runtime.Object {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	created := t.created
+	t.created = nil
+	return created
+}
+
+// Cleanup deletes all objects created through the client in reverse order of
+// their creation using foreground propagation and waits until they are
+// deleted. Objects, which already do not exist anymore, are ignored. The
+// returned error aggregates all objects, which could not be removed.
+//
+// The client has to be created using ClientWithTracking, otherwise Cleanup
+// does nothing. Afterwards the client starts recording from scratch.
+func (c *Client) Cleanup(ctx context.Context) error {
+	if c.tracker == nil {
+		return nil
+	}
+	return c.deleteInReverse(ctx, c.tracker.reset(),
+		client.PropagationPolicy(metav1.DeletePropagationForeground))
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2020 Testutil Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"context"
+	"time"
+
+	"github.com/kubism/testutil/pkg/rand"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+var _ = Describe("Cleanup", func() {
+	var trackingClient *Client
+	BeforeEach(func() {
+		var err error
+		trackingClient, err = NewClient(restConfig, ClientWithTracking())
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+	})
+	It("deletes all created objects", func() {
+		owner := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "cm-" + rand.String(5)},
+		}
+		gomega.Expect(trackingClient.Create(context.Background(), owner)).To(gomega.Succeed())
+		owned := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "default",
+				Name:      owner.Name + "-owned",
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: "v1",
+					Kind:       "ConfigMap",
+					Name:       owner.Name,
+					UID:        owner.UID,
+				}},
+			},
+		}
+		gomega.Expect(trackingClient.Create(context.Background(), owned)).To(gomega.Succeed())
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		gomega.Expect(trackingClient.Cleanup(ctx)).To(gomega.Succeed())
+		err := k8sClient.Get(context.Background(), NamespacedName(owner), owner)
+		gomega.Expect(apierrors.IsNotFound(err)).To(gomega.BeTrue())
+		err = k8sClient.Get(context.Background(), NamespacedName(owned), owned)
+		gomega.Expect(apierrors.IsNotFound(err)).To(gomega.BeTrue())
+		gomega.Expect(trackingClient.Cleanup(ctx)).To(gomega.Succeed())
+	})
+	It("deletes objects created by server-side apply", func() {
+		configMap := genApplyConfigMap("cm-"+rand.String(5), map[string]string{"foo": "bar"})
+		gomega.Expect(trackingClient.ServerSideApply(context.Background(), configMap, "operator", false)).To(gomega.Succeed())
+		gomega.Expect(trackingClient.ServerSideApply(context.Background(), configMap, "operator", false)).To(gomega.Succeed())
+		gomega.Expect(trackingClient.tracker.created).To(gomega.HaveLen(1))
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		gomega.Expect(trackingClient.Cleanup(ctx)).To(gomega.Succeed())
+		err := k8sClient.Get(context.Background(), NamespacedName(configMap), configMap)
+		gomega.Expect(apierrors.IsNotFound(err)).To(gomega.BeTrue())
+	})
+	It("ignores objects already deleted", func() {
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "cm-" + rand.String(5)},
+		}
+		gomega.Expect(trackingClient.Create(context.Background(), configMap)).To(gomega.Succeed())
+		gomega.Expect(k8sClient.Delete(context.Background(), configMap)).To(gomega.Succeed())
+		gomega.Expect(trackingClient.Cleanup(context.Background())).To(gomega.Succeed())
+	})
+	It("reports objects which could not be removed", func() {
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:  "default",
+				Name:       "cm-" + rand.String(5),
+				Finalizers: []string{testFinalizer},
+			},
+		}
+		gomega.Expect(trackingClient.Create(context.Background(), configMap)).To(gomega.Succeed())
+		defer k8sClient.Patch(context.Background(), configMap, removeFinalizersPatch) // nolint:errcheck
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		err := trackingClient.Cleanup(ctx)
+		gomega.Expect(err).To(gomega.HaveOccurred())
+		gomega.Expect(err.Error()).To(gomega.ContainSubstring(configMap.Name))
+	})
+	It("does nothing without tracking", func() {
+		configMap := mustCreateConfigMap()
+		defer k8sClient.Delete(context.Background(), configMap) // nolint:errcheck
+		gomega.Expect(k8sClient.Cleanup(context.Background())).To(gomega.Succeed())
+		gomega.Expect(k8sClient.Get(context.Background(), NamespacedName(configMap), configMap)).To(gomega.Succeed())
+	})
+})
@@ -0,0 +1,386 @@
+/*
+Copyright 2020 Testutil Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+var (
+	// errWatchFailed is returned by watchUntil if the watch could not be
+	// established or broke down, so the caller should fall back to polling.
+	errWatchFailed = errors.New("watch failed")
+	// errSubjectDeleted is returned by consumeWatch if the watched object
+	// was deleted.
+	errSubjectDeleted = errors.New("subject deleted")
+)
+
+// WaitUntil blocks until all conditions are met or the context is done.
+// The conditions are checked in sequence and their subjects are kept up to
+// date using a watch. If the watch can not be used or a condition combines
+// several subjects, the API server is polled using the interval and backoff
+// configured for the client.
+//
+// If the context is done before a condition is met, a *WaitError is returned
+// describing the last observed state of the subject.
+func (c *Client) WaitUntil(ctx context.Context, conditions ...Condition) error {
+	for _, condition := range conditions {
+		if err := c.waitFor(ctx, condition); err != nil {
+			if ctx.Err() != nil {
+				return c.newWaitError(condition, ctx.Err())
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// maxWaitErrorEvents limits the number of events attached to a WaitError.
+const maxWaitErrorEvents = 10
+
+// WaitError is returned by WaitUntil if a condition was not met before the
+// context was done. It contains the last observed state of the subject and
+// its most recent events to understand why the condition was not met.
+type WaitError struct {
+	// Condition is the description of the condition, which was not met.
+	Condition string
+	// Subject is a copy of the last observed subject of the condition.
+	Subject runtime.Object
+	// Status is the last observed status of the subject, if available.
+	Status map[string]interface{}
+	// Events are the most recent events regarding the subject.
+	Events []corev1.Event
+	// Logs are the most recent log lines, if waiting for a log line.
+	Logs []string
+	// Err is the error of the context.
+	Err error
+}
+
+func (e *WaitError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "condition %s not met", e.Condition)
+	if accessor, err := meta.Accessor(e.Subject); err == nil {
+		fmt.Fprintf(&b, " for %s/%s", accessor.GetNamespace(), accessor.GetName())
+	}
+	fmt.Fprintf(&b, ": %v", e.Err)
+	if e.Status != nil {
+		status, _ := json.Marshal(e.Status)
+		fmt.Fprintf(&b, "\nlast observed status: %s", status)
+	}
+	if len(e.Events) > 0 {
+		b.WriteString("\nrecent events:")
+		for _, event := range e.Events {
+			fmt.Fprintf(&b, "\n  %s %s (x%d): %s", event.Type, event.Reason, event.Count, event.Message)
+		}
+	}
+	if len(e.Logs) > 0 {
+		b.WriteString("\nrecent logs:")
+		for _, line := range e.Logs {
+			fmt.Fprintf(&b, "\n  %s", line)
+		}
+	}
+	return b.String()
+}
+
+func (e *WaitError) Unwrap() error {
+	return e.Err
+}
+
+// TerminalError is returned by WaitUntil if a condition detected, that its
+// subject reached a state it will not recover from, e.g. a container of a pod
+// which can not pull its image.
+type TerminalError struct {
+	// Kind of the object in a terminal state.
+	Kind string
+	// Object is the namespace and name of the object in a terminal state.
+	Object types.NamespacedName
+	// Container is the name of the affected container, if applicable.
+	Container string
+	// Reason is a brief CamelCase reason for the terminal state.
+	Reason string
+	// Message is a human-readable message with further details.
+	Message string
+}
+
+func (e *TerminalError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s is in terminal state", e.Kind, e.Object)
+	if e.Container != "" {
+		fmt.Fprintf(&b, ": container %s", e.Container)
+	}
+	fmt.Fprintf(&b, ": %s", e.Reason)
+	if e.Message != "" {
+		fmt.Fprintf(&b, ": %s", e.Message)
+	}
+	return b.String()
+}
+
+// newWaitError collects the diagnostics for a condition, which was not met.
+// As the original context is already done, events are retrieved using a
+// separate short-lived context.
+func (c *Client) newWaitError(condition Condition, err error) *WaitError {
+	waitErr := &WaitError{
+		Condition: condition.Description(),
+		Err:       err,
+	}
+	subject := condition.Subject()
+	if subject == nil {
+		return waitErr
+	}
+	waitErr.Subject = subject.DeepCopyObject()
+	if content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(subject); err == nil {
+		if status, ok := content["status"].(map[string]interface{}); ok {
+			waitErr.Status = status
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if events, err := c.Events(ctx, subject); err == nil {
+		sort.Slice(events, func(i, j int) bool {
+			return EventLastObserved(&events[i]).Before(EventLastObserved(&events[j]))
+		})
+		if len(events) > maxWaitErrorEvents {
+			events = events[len(events)-maxWaitErrorEvents:]
+		}
+		waitErr.Events = events
+	}
+	return waitErr
+}
+
+func (c *Client) waitFor(ctx context.Context, condition Condition) error {
+	subjects := conditionSubjects(condition)
+	if len(subjects) == 0 {
+		return fmt.Errorf("condition %s has no subject", condition.Description())
+	}
+	keys := make([]client.ObjectKey, len(subjects))
+	for i, subject := range subjects {
+		objectKey, err := client.ObjectKeyFromObject(subject)
+		if err != nil {
+			return err
+		}
+		keys[i] = objectKey
+	}
+	if err := c.refresh(ctx, keys, subjects); err != nil {
+		return err
+	}
+	if met, err := checkCondition(condition); met || err != nil {
+		return err
+	}
+	// Only conditions with a single subject can be watched, all others
+	// will be polled.
+	if !c.options.PollingOnly && len(subjects) == 1 {
+		err := c.watchUntil(ctx, keys[0], subjects[0], condition)
+		if !errors.Is(err, errWatchFailed) {
+			return err
+		}
+	}
+	return c.pollUntil(ctx, keys, subjects, condition)
+}
+
+// refresh retrieves the current state of all subjects.
+func (c *Client) refresh(ctx context.Context, keys []client.ObjectKey, subjects []runtime.Object) error {
+	for i, subject := range subjects {
+		if err := c.Get(ctx, keys[i], subject); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// watchUntil watches the subject and updates it in place for every change
+// until the condition is met. The watch is resumed from the last observed
+// resource version if the API server closes it.
+func (c *Client) watchUntil(ctx context.Context, key client.ObjectKey, subject runtime.Object, condition Condition) error {
+	resource, mapping, err := c.resourceFor(subject)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errWatchFailed, err)
+	}
+	accessor, err := meta.Accessor(subject)
+	if err != nil {
+		return err
+	}
+	// Conditions might depend on time, so they are re-evaluated regularly
+	// even if no changes are observed.
+	ticker := time.NewTicker(c.recheckInterval())
+	defer ticker.Stop()
+	resourceVersion := accessor.GetResourceVersion()
+	for {
+		w, err := resource.Watch(ctx, metav1.ListOptions{
+			FieldSelector:   fields.OneTermEqualSelector("metadata.name", key.Name).String(),
+			ResourceVersion: resourceVersion,
+		})
+		if err != nil {
+			return fmt.Errorf("%w: %v", errWatchFailed, err)
+		}
+		met, lastResourceVersion, err := c.consumeWatch(ctx, w, ticker.C, subject, condition)
+		w.Stop()
+		switch {
+		case met:
+			return nil
+		case apierrors.IsResourceExpired(err) || apierrors.IsGone(err):
+			// the resource version is too old, so start over with a fresh copy
+			if err := c.Get(ctx, key, subject); err != nil {
+				return err
+			}
+			if met, err := checkCondition(condition); met || err != nil {
+				return err
+			}
+			resourceVersion = accessor.GetResourceVersion()
+		case errors.Is(err, errSubjectDeleted):
+			return apierrors.NewNotFound(mapping.Resource.GroupResource(), key.Name)
+		case err != nil:
+			return err
+		case lastResourceVersion != "":
+			resourceVersion = lastResourceVersion
+		}
+	}
+}
+
+// consumeWatch processes events until the condition is met or the watch is
+// closed. In the latter case the last observed resource version is returned,
+// so the watch can be resumed.
+func (c *Client) consumeWatch(ctx context.Context, w watch.Interface, tick <-chan time.Time, subject runtime.Object, condition Condition) (bool, string, error) {
+	resourceVersion := ""
+	for {
+		select {
+		case <-ctx.Done():
+			return false, resourceVersion, ctx.Err()
+		case <-tick:
+			if met, err := checkCondition(condition); met || err != nil {
+				return met, resourceVersion, err
+			}
+		case event, ok := <-w.ResultChan():
+			if !ok {
+				return false, resourceVersion, nil
+			}
+			switch event.Type {
+			case watch.Error:
+				err := apierrors.FromObject(event.Object)
+				if apierrors.IsResourceExpired(err) || apierrors.IsGone(err) {
+					return false, resourceVersion, err
+				}
+				return false, resourceVersion, fmt.Errorf("%w: %v", errWatchFailed, err)
+			case watch.Deleted:
+				return false, resourceVersion, errSubjectDeleted
+			case watch.Added, watch.Modified:
+				obj, ok := event.Object.(*unstructured.Unstructured)
+				if !ok {
+					return false, resourceVersion, fmt.Errorf("%w: unexpected object of type %T", errWatchFailed, event.Object)
+				}
+				if err := setFromUnstructured(subject, obj); err != nil {
+					return false, resourceVersion, err
+				}
+				if met, err := checkCondition(condition); met || err != nil {
+					return met, resourceVersion, err
+				}
+				resourceVersion = obj.GetResourceVersion()
+			}
+		}
+	}
+}
+
+// pollUntil retrieves the subjects of the condition until it is met. The
+// interval between the requests increases exponentially.
+func (c *Client) pollUntil(ctx context.Context, keys []client.ObjectKey, subjects []runtime.Object, condition Condition) error {
+	backoff := c.pollBackoff()
+	for {
+		timer := time.NewTimer(backoff.Step())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		if err := c.refresh(ctx, keys, subjects); err != nil {
+			return err
+		}
+		if met, err := checkCondition(condition); met || err != nil {
+			return err
+		}
+	}
+}
+
+// recheckInterval returns the poll interval of the client. It falls back to
+// defaultPollInterval if the interval is not positive, e.g. for clients not
+// created using NewClient.
+func (c *Client) recheckInterval() time.Duration {
+	if c.options.PollInterval <= 0 {
+		return defaultPollInterval
+	}
+	return c.options.PollInterval
+}
+
+// pollBackoff returns the backoff for polling as configured for the client.
+func (c *Client) pollBackoff() wait.Backoff {
+	return wait.Backoff{
+		Duration: c.recheckInterval(),
+		Factor:   c.options.PollBackoffFactor,
+		Cap:      c.options.PollMaxInterval,
+		Steps:    math.MaxInt32,
+	}
+}
+
+// setFromUnstructured overwrites obj with the content of u.
+func setFromUnstructured(obj runtime.Object, u *unstructured.Unstructured) error {
+	if target, ok := obj.(*unstructured.Unstructured); ok {
+		target.SetUnstructuredContent(u.DeepCopy().UnstructuredContent())
+		return nil
+	}
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), obj)
+}
+
+// resourceFor returns a dynamic client for the resource of the provided
+// object as well as the mapping used to find the resource.
+func (c *Client) resourceFor(obj runtime.Object) (dynamic.ResourceInterface, *meta.RESTMapping, error) {
+	gvk, err := apiutil.GVKForObject(obj, c.scheme)
+	if err != nil {
+		return nil, nil, err
+	}
+	mapping, err := c.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, nil, err
+	}
+	if mapping.Scope.Name() == meta.RESTScopeNameRoot {
+		return c.dynamicClient.Resource(mapping.Resource), mapping, nil
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c.dynamicClient.Resource(mapping.Resource).Namespace(accessor.GetNamespace()), mapping, nil
+}